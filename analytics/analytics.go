@@ -0,0 +1,206 @@
+// Package analytics computes callback delivery reports from pages of events and callback history.
+package analytics
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"github.com/google/uuid"
+)
+
+// DefaultTopReasons is the number of failure reasons kept in a report when Collector.TopReasons is zero.
+const DefaultTopReasons = 10
+
+// Collector accumulates pages of events and callback history.
+// Pages may be added in any order, records seen twice are counted once.
+type Collector struct {
+	// TopReasons is the number of most frequent failure reasons kept in the report
+	TopReasons int
+
+	attempts map[uuid.UUID]callback.CallbackHistory
+	events   map[uuid.UUID]callback.Event
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		attempts: make(map[uuid.UUID]callback.CallbackHistory),
+		events:   make(map[uuid.UUID]callback.Event),
+	}
+}
+
+// AddEvents adds a page returned by Client.GetListOfEvents.
+func (c *Collector) AddEvents(list *callback.EventList) {
+	if list == nil {
+		return
+	}
+	for _, e := range list.Data {
+		c.events[e.ID] = e
+	}
+}
+
+// AddHistory adds a page returned by Client.GetCallbackHistoryByEventID.
+func (c *Collector) AddHistory(list *callback.CallbackHistoryList) {
+	if list == nil {
+		return
+	}
+	for _, h := range list.Data {
+		c.attempts[h.ID] = h
+	}
+}
+
+// Report computes the delivery report of everything collected so far.
+func (c *Collector) Report() *Report {
+	report := &Report{
+		Attempts: len(c.attempts),
+		Events:   len(c.events),
+	}
+
+	hosts := make(map[string]*HostStats)
+	reasons := make(map[string]int)
+	latencies := make([]time.Duration, 0, len(c.attempts))
+	created := make(map[uuid.UUID]time.Time)
+	firstSuccess := make(map[uuid.UUID]time.Time)
+
+	for _, e := range c.events {
+		created[e.ID] = e.CreatedAt
+	}
+
+	for _, h := range c.attempts {
+		host := attemptHost(h)
+		stats, ok := hosts[host]
+		if !ok {
+			stats = &HostStats{Host: host}
+			hosts[host] = stats
+		}
+		stats.Attempts++
+
+		if h.Duration > 0 {
+			latencies = append(latencies, h.Duration)
+		}
+
+		if _, ok := created[h.Event.ID]; !ok && !h.Event.CreatedAt.IsZero() {
+			created[h.Event.ID] = h.Event.CreatedAt
+		}
+
+		if h.Status == callback.StatusSucceeded {
+			stats.Succeeded++
+			if at, ok := firstSuccess[h.Event.ID]; !ok || h.CreatedAt.Before(at) {
+				firstSuccess[h.Event.ID] = h.CreatedAt
+			}
+			continue
+		}
+
+		stats.Failed++
+		if h.ReasonFailed != "" {
+			reasons[h.ReasonFailed]++
+		}
+	}
+
+	for _, stats := range hosts {
+		stats.SuccessRate = float64(stats.Succeeded) / float64(stats.Attempts)
+		report.Hosts = append(report.Hosts, *stats)
+	}
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		if report.Hosts[i].Attempts != report.Hosts[j].Attempts {
+			return report.Hosts[i].Attempts > report.Hosts[j].Attempts
+		}
+		return report.Hosts[i].Host < report.Hosts[j].Host
+	})
+
+	report.Latency = percentiles(latencies)
+
+	var toFirstSuccess []time.Duration
+	for id, at := range firstSuccess {
+		if start, ok := created[id]; ok && !start.IsZero() && !at.Before(start) {
+			toFirstSuccess = append(toFirstSuccess, at.Sub(start))
+		}
+	}
+	report.TimeToFirstSuccess = percentiles(toFirstSuccess)
+
+	report.RetryDistribution = c.retryDistribution()
+	report.TopFailureReasons = topReasons(reasons, c.topReasons())
+
+	return report
+}
+
+func (c *Collector) topReasons() int {
+	if c.TopReasons > 0 {
+		return c.TopReasons
+	}
+	return DefaultTopReasons
+}
+
+func (c *Collector) retryDistribution() []RetryBucket {
+	counts := make(map[int64]int)
+	for _, e := range c.events {
+		counts[e.RetryCount]++
+	}
+
+	buckets := make([]RetryBucket, 0, len(counts))
+	for retries, events := range counts {
+		buckets = append(buckets, RetryBucket{RetryCount: retries, Events: events})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].RetryCount < buckets[j].RetryCount
+	})
+	return buckets
+}
+
+func topReasons(reasons map[string]int, n int) []ReasonCount {
+	top := make([]ReasonCount, 0, len(reasons))
+	for reason, count := range reasons {
+		top = append(top, ReasonCount{Reason: reason, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Reason < top[j].Reason
+	})
+
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// attemptHost returns the host an attempt was sent to.
+func attemptHost(h callback.CallbackHistory) string {
+	target := h.TargetURL
+	if target == "" {
+		target = h.Event.CallbackURL
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// percentiles returns the nearest-rank percentiles of durations.
+func percentiles(durations []time.Duration) Percentiles {
+	p := Percentiles{Count: len(durations)}
+	if len(durations) == 0 {
+		return p
+	}
+
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	rank := func(q float64) time.Duration {
+		i := int(math.Ceil(q*float64(len(durations)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return durations[i]
+	}
+
+	p.P50 = rank(0.50)
+	p.P95 = rank(0.95)
+	return p
+}
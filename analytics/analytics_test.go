@@ -0,0 +1,91 @@
+package analytics
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"github.com/google/uuid"
+)
+
+func TestCollectorReport(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := callback.Event{ID: uuid.New(), CallbackURL: "https://a.example.com/callback", RetryCount: 2, CreatedAt: created}
+	second := callback.Event{ID: uuid.New(), CallbackURL: "https://b.example.com/callback", RetryCount: 1, CreatedAt: created}
+
+	history := &callback.CallbackHistoryList{
+		Data: []callback.CallbackHistory{
+			{
+				ID:           uuid.New(),
+				Event:        first,
+				Status:       callback.StatusFailed,
+				ReasonFailed: "webhook rejected by service with statuscode 500",
+				Duration:     100 * time.Millisecond,
+				CreatedAt:    created.Add(time.Second),
+			},
+			{
+				ID:        uuid.New(),
+				Event:     first,
+				Status:    callback.StatusSucceeded,
+				Duration:  300 * time.Millisecond,
+				CreatedAt: created.Add(time.Minute),
+			},
+			{
+				ID:        uuid.New(),
+				Event:     second,
+				Status:    callback.StatusSucceeded,
+				Duration:  200 * time.Millisecond,
+				CreatedAt: created.Add(10 * time.Second),
+			},
+		},
+	}
+
+	c := NewCollector()
+	c.AddEvents(&callback.EventList{Data: []callback.Event{first, second}})
+	c.AddHistory(history)
+	// pages added twice are counted once
+	c.AddHistory(history)
+
+	got := c.Report()
+
+	wantHosts := []HostStats{
+		{Host: "a.example.com", Attempts: 2, Succeeded: 1, Failed: 1, SuccessRate: 0.5},
+		{Host: "b.example.com", Attempts: 1, Succeeded: 1, SuccessRate: 1},
+	}
+	if !reflect.DeepEqual(got.Hosts, wantHosts) {
+		t.Errorf("expected hosts %+v, but got %+v", wantHosts, got.Hosts)
+	}
+
+	wantLatency := Percentiles{Count: 3, P50: 200 * time.Millisecond, P95: 300 * time.Millisecond}
+	if got.Latency != wantLatency {
+		t.Errorf("expected latency %+v, but got %+v", wantLatency, got.Latency)
+	}
+
+	wantFirstSuccess := Percentiles{Count: 2, P50: 10 * time.Second, P95: time.Minute}
+	if got.TimeToFirstSuccess != wantFirstSuccess {
+		t.Errorf("expected time to first success %+v, but got %+v", wantFirstSuccess, got.TimeToFirstSuccess)
+	}
+
+	wantRetries := []RetryBucket{{RetryCount: 1, Events: 1}, {RetryCount: 2, Events: 1}}
+	if !reflect.DeepEqual(got.RetryDistribution, wantRetries) {
+		t.Errorf("expected retry distribution %+v, but got %+v", wantRetries, got.RetryDistribution)
+	}
+
+	wantReasons := []ReasonCount{{Reason: "webhook rejected by service with statuscode 500", Count: 1}}
+	if !reflect.DeepEqual(got.TopFailureReasons, wantReasons) {
+		t.Errorf("expected failure reasons %+v, but got %+v", wantReasons, got.TopFailureReasons)
+	}
+
+	var text bytes.Buffer
+	if err := got.WriteText(&text); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+
+	if !strings.Contains(text.String(), "a.example.com") {
+		t.Errorf("expected text report to contain host a.example.com, but got\n%s", text.String())
+	}
+}
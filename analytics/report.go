@@ -0,0 +1,91 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+type Report struct {
+	// Attempts is the number of callback attempts the report is computed from
+	Attempts int `json:"attempts"`
+	// Events is the number of events the report is computed from
+	Events int `json:"events"`
+	// Hosts holds the delivery stats of each callback host, busiest first
+	Hosts []HostStats `json:"hosts"`
+	// Latency is the duration of the callback attempts
+	Latency Percentiles `json:"latency"`
+	// TimeToFirstSuccess is the time between the creation of an event and its first successful attempt
+	TimeToFirstSuccess Percentiles `json:"time_to_first_success"`
+	// RetryDistribution is the number of events per retry count
+	RetryDistribution []RetryBucket `json:"retry_distribution"`
+	// TopFailureReasons are the most frequent reasons of failed attempts
+	TopFailureReasons []ReasonCount `json:"top_failure_reasons"`
+}
+
+type HostStats struct {
+	Host        string  `json:"host"`
+	Attempts    int     `json:"attempts"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+type Percentiles struct {
+	// Count is the number of samples
+	Count int           `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+}
+
+type RetryBucket struct {
+	RetryCount int64 `json:"retry_count"`
+	Events     int   `json:"events"`
+}
+
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as plain text tables.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Events\t%d\n", r.Events)
+	fmt.Fprintf(tw, "Attempts\t%d\n", r.Attempts)
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "HOST\tATTEMPTS\tSUCCEEDED\tFAILED\tSUCCESS RATE")
+	for _, h := range r.Hosts {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f%%\n", h.Host, h.Attempts, h.Succeeded, h.Failed, h.SuccessRate*100)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "METRIC\tCOUNT\tP50\tP95")
+	fmt.Fprintf(tw, "attempt latency\t%d\t%s\t%s\n", r.Latency.Count, r.Latency.P50, r.Latency.P95)
+	fmt.Fprintf(tw, "time to first success\t%d\t%s\t%s\n", r.TimeToFirstSuccess.Count, r.TimeToFirstSuccess.P50, r.TimeToFirstSuccess.P95)
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "RETRY COUNT\tEVENTS")
+	for _, b := range r.RetryDistribution {
+		fmt.Fprintf(tw, "%d\t%d\n", b.RetryCount, b.Events)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "FAILURE REASON\tCOUNT")
+	for _, reason := range r.TopFailureReasons {
+		fmt.Fprintf(tw, "%s\t%d\n", reason.Reason, reason.Count)
+	}
+
+	return tw.Flush()
+}
@@ -1,6 +1,8 @@
 package callbackclient
 
 import (
+	"encoding/json"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,8 +14,54 @@ type CallbackHistory struct {
 	Status       Status    `json:"status,omitempty" example:"FAILED"`
 	ResponseCode int64     `json:"response_code,omitempty" example:"200"`
 	ReasonFailed string    `json:"reason_failed,omitempty"`
-	CreatedAt    time.Time `json:"created_at,omitempty" example:"2023-09-11T14:30:00Z"`
-	UpdatedAt    time.Time `json:"updated_at,omitempty" example:"2023-09-11T14:30:00Z"`
+	// AttemptNumber is the position of this attempt among the deliveries of the event, starting at 1
+	AttemptNumber int64 `json:"attempt_number,omitempty" example:"1"`
+	// Duration is the time between sending the callback and receiving the response,
+	// sent as duration_ms in whole milliseconds like the intervals of RetryPolicy
+	Duration time.Duration `json:"-"`
+	// TargetURL is the url the callback was sent to
+	TargetURL string `json:"target_url,omitempty" example:"https://service.com/callback"`
+	// RequestHeaders are the headers sent with the callback, with signatures redacted
+	RequestHeaders http.Header `json:"request_headers,omitempty"`
+	// ResponseHeaders are the headers returned by the receiver
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	// ResponseBody is the body returned by the receiver, truncated to ResponseBodyLimit bytes
+	ResponseBody string `json:"response_body,omitempty"`
+	// ResponseBodyTruncated is true if ResponseBody was cut to ResponseBodyLimit bytes
+	ResponseBodyTruncated bool      `json:"response_body_truncated,omitempty"`
+	CreatedAt             time.Time `json:"created_at,omitempty" example:"2023-09-11T14:30:00Z"`
+	UpdatedAt             time.Time `json:"updated_at,omitempty" example:"2023-09-11T14:30:00Z"`
+}
+
+// callbackHistoryJSON is the wire format of CallbackHistory.
+type callbackHistoryJSON struct {
+	DurationMS int64 `json:"duration_ms,omitempty" example:"120"`
+}
+
+// MarshalJSON encodes the duration of the attempt in milliseconds.
+func (h CallbackHistory) MarshalJSON() ([]byte, error) {
+	type history CallbackHistory
+	return json.Marshal(struct {
+		history
+		callbackHistoryJSON
+	}{
+		history:             history(h),
+		callbackHistoryJSON: callbackHistoryJSON{DurationMS: h.Duration.Milliseconds()},
+	})
+}
+
+// UnmarshalJSON decodes a history with the duration of the attempt in milliseconds.
+func (h *CallbackHistory) UnmarshalJSON(data []byte) error {
+	type history CallbackHistory
+	w := struct {
+		*history
+		callbackHistoryJSON
+	}{history: (*history)(h)}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	h.Duration = time.Duration(w.DurationMS) * time.Millisecond
+	return nil
 }
 
 type CallbackHistoryList struct {
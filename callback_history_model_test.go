@@ -0,0 +1,37 @@
+package callbackclient_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+func TestCallbackHistoryJSON(t *testing.T) {
+	history := callback.CallbackHistory{
+		Status:        callback.StatusFailed,
+		ResponseCode:  500,
+		AttemptNumber: 2,
+		Duration:      1500 * time.Millisecond,
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if !strings.Contains(string(data), `"duration_ms":1500`) || strings.Contains(string(data), `"duration"`) {
+		t.Errorf("expected duration in milliseconds, but got %s", data)
+	}
+
+	var got callback.CallbackHistory
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if got.Duration != history.Duration || got.AttemptNumber != history.AttemptNumber || got.Status != history.Status {
+		t.Errorf("expected to get %+v, but got %+v", history, got)
+	}
+}
@@ -0,0 +1,229 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWorkers is the number of webhooks an AsyncReceiver handles concurrently by default.
+	DefaultWorkers = 4
+	// DefaultQueueSize is the number of accepted webhooks an AsyncReceiver holds by default.
+	DefaultQueueSize = 100
+	// QueueFullRetryAfter is the Retry-After sent by default for a callback that found the queue full.
+	QueueFullRetryAfter = 30 * time.Second
+)
+
+// ErrReceiverClosed is returned by a second Shutdown and reported for the webhooks
+// dropped when the receiver could not drain its queue in time.
+var ErrReceiverClosed = errors.New("receiver closed")
+
+// AsyncHandler handles a verified webhook after its callback was acknowledged.
+type AsyncHandler func(ctx context.Context, webhook *Webhook) error
+
+// FailureHandler is called with the error of an AsyncHandler, as the sender already
+// got a 202 and will not retry the callback.
+type FailureHandler func(ctx context.Context, webhook *Webhook, err error)
+
+// AsyncReceiver acknowledges callbacks at once with a 202 and handles them on a bounded worker pool,
+// so slow handlers do not exceed the timeout of the sender and cause spurious retries.
+// It must run behind Verifier.Middleware, requests without a verified webhook are rejected with ErrNotVerified.
+//
+// Callbacks arriving while the queue is full get a 503 with a Retry-After header,
+// so the sender retries them later. Handler failures are only reported to the FailureHandler.
+// Behind Idempotent, an accepted callback is recorded as succeeded whatever its handler returns.
+type AsyncReceiver struct {
+	handler      AsyncHandler
+	workers      int
+	queueSize    int
+	retryAfter   time.Duration
+	persist      func(ctx context.Context, webhook *Webhook) error
+	onFailure    FailureHandler
+	errorHandler ErrorHandler
+
+	mu     sync.RWMutex
+	closed bool
+	// slots holds a token per accepted webhook until it was handled,
+	// so a callback is only persisted once there is room for it
+	slots chan struct{}
+	queue chan job
+	abort chan struct{}
+	done  chan struct{}
+}
+
+// job is a webhook waiting for a worker, with the context of its request.
+type job struct {
+	ctx     context.Context
+	webhook *Webhook
+}
+
+type AsyncOption func(*AsyncReceiver)
+
+// WithWorkers sets the number of webhooks handled concurrently, DefaultWorkers by default.
+func WithWorkers(n int) AsyncOption {
+	return func(a *AsyncReceiver) {
+		a.workers = n
+	}
+}
+
+// WithQueueSize sets the number of accepted webhooks waiting for a worker, DefaultQueueSize by default.
+// With 0 callbacks are only accepted by an idle worker.
+func WithQueueSize(n int) AsyncOption {
+	return func(a *AsyncReceiver) {
+		a.queueSize = n
+	}
+}
+
+// WithQueueRetryAfter sets the Retry-After sent when the queue is full, QueueFullRetryAfter by default.
+func WithQueueRetryAfter(d time.Duration) AsyncOption {
+	return func(a *AsyncReceiver) {
+		a.retryAfter = d
+	}
+}
+
+// WithPersist sets a function storing each webhook before it is acknowledged,
+// e.g. in an outbox table, so it survives a crash before a worker handled it.
+// It is only called once the receiver has room for the webhook, so a callback that found
+// the queue full is not stored before the sender retries it.
+// A callback whose webhook could not be stored gets a 500 and is retried by the sender.
+func WithPersist(persist func(ctx context.Context, webhook *Webhook) error) AsyncOption {
+	return func(a *AsyncReceiver) {
+		a.persist = persist
+	}
+}
+
+// WithFailureHandler sets the function reporting handler failures.
+func WithFailureHandler(h FailureHandler) AsyncOption {
+	return func(a *AsyncReceiver) {
+		a.onFailure = h
+	}
+}
+
+// WithAsyncErrorHandler sets how the receiver responds to callbacks it could not accept.
+func WithAsyncErrorHandler(h ErrorHandler) AsyncOption {
+	return func(a *AsyncReceiver) {
+		a.errorHandler = h
+	}
+}
+
+// NewAsyncReceiver returns a receiver handling webhooks with h and starts its workers.
+// Shutdown stops them.
+func NewAsyncReceiver(h AsyncHandler, opts ...AsyncOption) *AsyncReceiver {
+	a := &AsyncReceiver{
+		handler:      h,
+		workers:      DefaultWorkers,
+		queueSize:    DefaultQueueSize,
+		retryAfter:   QueueFullRetryAfter,
+		onFailure:    func(context.Context, *Webhook, error) {},
+		errorHandler: DefaultErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.workers = max(a.workers, 1)
+	a.queueSize = max(a.queueSize, 0)
+
+	a.slots = make(chan struct{}, a.workers+a.queueSize)
+	a.queue = make(chan job, a.queueSize)
+	a.abort = make(chan struct{})
+	a.done = make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(a.workers)
+	for range a.workers {
+		go func() {
+			defer wg.Done()
+			for j := range a.queue {
+				select {
+				case <-a.abort:
+					a.onFailure(j.ctx, j.webhook, ErrReceiverClosed)
+				default:
+					a.handle(j)
+				}
+				<-a.slots
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(a.done)
+	}()
+	return a
+}
+
+func (a *AsyncReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := FromContext(r.Context())
+	if !ok {
+		// never acknowledge a payload nobody verified
+		a.errorHandler(w, r, ErrNotVerified)
+		return
+	}
+
+	// the webhook outlives the request, it keeps its values but not its cancellation
+	ctx := context.WithoutCancel(r.Context())
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		RetryLater(w, a.retryAfter)
+		return
+	}
+
+	select {
+	case a.slots <- struct{}{}:
+	default:
+		RetryLater(w, a.retryAfter)
+		return
+	}
+
+	if a.persist != nil {
+		if err := a.persist(r.Context(), webhook); err != nil {
+			<-a.slots
+			a.errorHandler(w, r, fmt.Errorf("%w: %w", ErrHandlerFailed, err))
+			return
+		}
+	}
+
+	// the slot guarantees a worker takes the job as soon as it is done with its current one
+	a.queue <- job{ctx: ctx, webhook: webhook}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handle runs the handler of a job, reporting its error or panic to the FailureHandler.
+func (a *AsyncReceiver) handle(j job) {
+	defer func() {
+		if p := recover(); p != nil {
+			a.onFailure(j.ctx, j.webhook, fmt.Errorf("%w: panic: %v", ErrHandlerFailed, p))
+		}
+	}()
+
+	if err := a.handler(j.ctx, j.webhook); err != nil {
+		a.onFailure(j.ctx, j.webhook, fmt.Errorf("%w: %w", ErrHandlerFailed, err))
+	}
+}
+
+// Shutdown stops accepting callbacks, which then get a 503, and waits until the workers handled
+// every accepted webhook or ctx is done. Webhooks still queued when ctx is done are dropped
+// and reported to the FailureHandler with ErrReceiverClosed, the ones being handled run to completion.
+func (a *AsyncReceiver) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrReceiverClosed
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		close(a.abort)
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,257 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sendAsync(h http.Handler, body string) *httptest.ResponseRecorder {
+	timestamp := "1700000000"
+	r := httptest.NewRequest(http.MethodPost, "/v1/callback", strings.NewReader(body))
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(SignatureHeader, sign(testSecret, timestamp, body))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAsyncReceiver(t *testing.T) {
+	handled := make(chan string, 1)
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		handled <- string(webhook.Payload)
+		return nil
+	})
+	defer receiver.Shutdown(context.Background())
+
+	payload := `{"event":"payment_success"}`
+	w := sendAsync(NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver), payload)
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected to get %v, but got %v", http.StatusAccepted, w.Code)
+		return
+	}
+
+	select {
+	case got := <-handled:
+		if got != payload {
+			t.Errorf("expected to get %s, but got %s", payload, got)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected webhook to be handled")
+	}
+}
+
+func TestAsyncReceiverQueueFull(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, WithWorkers(1), WithQueueSize(1), WithQueueRetryAfter(time.Minute))
+	handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver)
+
+	if w := sendAsync(handler, `{"event":"first"}`); w.Code != http.StatusAccepted {
+		t.Errorf("expected to get %v, but got %v", http.StatusAccepted, w.Code)
+	}
+	<-started
+
+	if w := sendAsync(handler, `{"event":"second"}`); w.Code != http.StatusAccepted {
+		t.Errorf("expected queued callback to get %v, but got %v", http.StatusAccepted, w.Code)
+	}
+
+	w := sendAsync(handler, `{"event":"third"}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected to get %v, but got %v", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, but got %q", got)
+	}
+
+	close(release)
+	<-started
+	if err := receiver.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+}
+
+func TestAsyncReceiverFailures(t *testing.T) {
+	var mu sync.Mutex
+	var failures []error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		if strings.Contains(string(webhook.Payload), "panic") {
+			panic("handler bug")
+		}
+		return errors.New("database unavailable")
+	}, WithFailureHandler(func(ctx context.Context, webhook *Webhook, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, err)
+		wg.Done()
+	}))
+	handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver)
+
+	sendAsync(handler, `{"event":"fail"}`)
+	sendAsync(handler, `{"event":"panic"}`)
+	wg.Wait()
+
+	if err := receiver.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+	for _, err := range failures {
+		if !errors.Is(err, ErrHandlerFailed) {
+			t.Errorf("expected to get %v, but got %v", ErrHandlerFailed, err)
+		}
+	}
+}
+
+func TestAsyncReceiverPersist(t *testing.T) {
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		return nil
+	}, WithPersist(func(ctx context.Context, webhook *Webhook) error {
+		return errors.New("outbox unavailable")
+	}))
+	defer receiver.Shutdown(context.Background())
+
+	w := sendAsync(NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver), `{"event":"payment_success"}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected to get %v, but got %v", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestAsyncReceiverPersistQueueFull(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var persisted []string
+	failPersist := true
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, WithWorkers(1), WithQueueSize(1), WithPersist(func(ctx context.Context, webhook *Webhook) error {
+		if failPersist {
+			failPersist = false
+			return errors.New("outbox unavailable")
+		}
+		persisted = append(persisted, string(webhook.Payload))
+		return nil
+	}))
+	handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver)
+
+	// a failed persist gives its slot back
+	if w := sendAsync(handler, `{"event":"lost"}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected to get %v, but got %v", http.StatusInternalServerError, w.Code)
+	}
+
+	if w := sendAsync(handler, `{"event":"first"}`); w.Code != http.StatusAccepted {
+		t.Errorf("expected to get %v, but got %v", http.StatusAccepted, w.Code)
+	}
+	<-started
+	if w := sendAsync(handler, `{"event":"second"}`); w.Code != http.StatusAccepted {
+		t.Errorf("expected queued callback to get %v, but got %v", http.StatusAccepted, w.Code)
+	}
+	if w := sendAsync(handler, `{"event":"third"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected to get %v, but got %v", http.StatusServiceUnavailable, w.Code)
+	}
+
+	want := []string{`{"event":"first"}`, `{"event":"second"}`}
+	if !reflect.DeepEqual(persisted, want) {
+		t.Errorf("expected to persist %v, but got %v", want, persisted)
+	}
+
+	close(release)
+	<-started
+	if err := receiver.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+}
+
+func TestAsyncReceiverNotVerified(t *testing.T) {
+	handled := false
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		handled = true
+		return nil
+	})
+
+	w := sendAsync(receiver, `{"event":"payment_success"}`)
+	if err := receiver.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected to get %v, but got %v", http.StatusInternalServerError, w.Code)
+	}
+	if handled {
+		t.Errorf("expected unverified callback not to be handled")
+	}
+}
+
+func TestAsyncReceiverShutdown(t *testing.T) {
+	var mu sync.Mutex
+	handled := 0
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		handled++
+		return nil
+	}, WithWorkers(2))
+	handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver)
+
+	for range 5 {
+		if w := sendAsync(handler, `{"event":"payment_success"}`); w.Code != http.StatusAccepted {
+			t.Errorf("expected to get %v, but got %v", http.StatusAccepted, w.Code)
+		}
+	}
+
+	if err := receiver.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+	if handled != 5 {
+		t.Errorf("expected 5 webhooks to be drained, but got %d", handled)
+	}
+
+	if w := sendAsync(handler, `{"event":"payment_success"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected callback after shutdown to get %v, but got %v", http.StatusServiceUnavailable, w.Code)
+	}
+	if err := receiver.Shutdown(context.Background()); !errors.Is(err, ErrReceiverClosed) {
+		t.Errorf("expected to get %v, but got %v", ErrReceiverClosed, err)
+	}
+}
+
+func TestAsyncReceiverShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	dropped := make(chan error, 1)
+	receiver := NewAsyncReceiver(func(ctx context.Context, webhook *Webhook) error {
+		close(started)
+		<-release
+		return nil
+	}, WithWorkers(1), WithFailureHandler(func(ctx context.Context, webhook *Webhook, err error) {
+		dropped <- err
+	}))
+	handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(receiver)
+
+	sendAsync(handler, `{"event":"first"}`)
+	<-started
+	sendAsync(handler, `{"event":"second"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := receiver.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected to get %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := <-dropped; !errors.Is(err, ErrReceiverClosed) {
+		t.Errorf("expected queued webhook to be dropped with %v, but got %v", ErrReceiverClosed, err)
+	}
+}
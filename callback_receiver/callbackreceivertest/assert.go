@@ -0,0 +1,53 @@
+package callbackreceivertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Serve records the response of h to r.
+func Serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// AssertStatus fails the test when the recorded status is not want.
+func AssertStatus(t testing.TB, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Errorf("expected status %d, but got %d: %s", want, w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+// AssertAccepted fails the test when the callback was not acknowledged with a 2xx status.
+func AssertAccepted(t testing.TB, w *httptest.ResponseRecorder) {
+	t.Helper()
+	if w.Code < 200 || w.Code >= 300 {
+		t.Errorf("expected callback to be accepted, but got status %d: %s", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+// AssertRejected fails the test when the callback was not rejected as unauthenticated with a 401.
+func AssertRejected(t testing.TB, w *httptest.ResponseRecorder) {
+	t.Helper()
+	AssertStatus(t, w, http.StatusUnauthorized)
+}
+
+// AssertHeader fails the test when the recorded header name is not want.
+func AssertHeader(t testing.TB, w *httptest.ResponseRecorder, name, want string) {
+	t.Helper()
+	if got := w.Header().Get(name); got != want {
+		t.Errorf("expected header %s %q, but got %q", name, want, got)
+	}
+}
+
+// AssertBodyContains fails the test when the recorded body does not contain substr.
+func AssertBodyContains(t testing.TB, w *httptest.ResponseRecorder, substr string) {
+	t.Helper()
+	if !strings.Contains(w.Body.String(), substr) {
+		t.Errorf("expected body to contain %q, but got %q", substr, w.Body.String())
+	}
+}
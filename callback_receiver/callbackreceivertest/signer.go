@@ -0,0 +1,266 @@
+// Package callbackreceivertest provides utilities to test callback receivers:
+// a Signer building callbacks signed as the callback service does and
+// assertions on the responses recorded by httptest.
+package callbackreceivertest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
+	"github.com/google/uuid"
+)
+
+// DefaultTarget is the url of the requests built by a Signer.
+const DefaultTarget = "/callback"
+
+// Signer builds signed callback requests.
+type Signer struct {
+	secret   string
+	now      func() time.Time
+	versions []callbackreceiver.SignatureVersion
+	keyID    string
+	key      ed25519.PrivateKey
+	standard bool
+}
+
+type SignerOption func(*Signer)
+
+// WithClock sets the clock callbacks are signed with, time.Now by default.
+func WithClock(now func() time.Time) SignerOption {
+	return func(s *Signer) {
+		s.now = now
+	}
+}
+
+// WithVersions makes the signer send the versioned signature header with a signature of each version
+// instead of the legacy one.
+func WithVersions(versions ...callbackreceiver.SignatureVersion) SignerOption {
+	return func(s *Signer) {
+		s.versions = versions
+	}
+}
+
+// WithEd25519Key makes the signer also sign callbacks with the Ed25519 key of id.
+func WithEd25519Key(id string, key ed25519.PrivateKey) SignerOption {
+	return func(s *Signer) {
+		s.keyID = id
+		s.key = key
+	}
+}
+
+// WithStandardWebhooks makes the signer also sign callbacks as in the Standard Webhooks specification,
+// with the webhook-id, webhook-timestamp and webhook-signature headers. The secret may be a "whsec_" secret.
+// Legacy callbacks without event id are not signed this way.
+func WithStandardWebhooks() SignerOption {
+	return func(s *Signer) {
+		s.standard = true
+	}
+}
+
+// NewSigner returns a signer signing callbacks with secret.
+func NewSigner(secret string, opts ...SignerOption) *Signer {
+	s := &Signer{
+		secret: secret,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// callback is a callback being built by a Signer.
+type callback struct {
+	method     string
+	target     string
+	secret     string
+	signedAt   time.Time
+	eventID    string
+	attempt    int
+	deliveryID string
+	// body replaces the payload after signing
+	body    []byte
+	without []string
+	header  http.Header
+}
+
+type RequestOption func(*callback)
+
+// WithEventID sets the event id, a random one by default.
+// An empty id sends a legacy callback without the event id header.
+func WithEventID(id string) RequestOption {
+	return func(c *callback) {
+		c.eventID = id
+	}
+}
+
+// WithAttempt sets the delivery attempt number, 1 by default.
+func WithAttempt(n int) RequestOption {
+	return func(c *callback) {
+		c.attempt = n
+	}
+}
+
+// WithDeliveryID sets the delivery id, a random one by default.
+func WithDeliveryID(id string) RequestOption {
+	return func(c *callback) {
+		c.deliveryID = id
+	}
+}
+
+// WithSecret signs the callback with secret instead of the secret of the signer.
+func WithSecret(secret string) RequestOption {
+	return func(c *callback) {
+		c.secret = secret
+	}
+}
+
+// WithTime signs the callback at t instead of the time of the signer clock.
+func WithTime(t time.Time) RequestOption {
+	return func(c *callback) {
+		c.signedAt = t
+	}
+}
+
+// WithMethod sets the method of the request, POST by default.
+func WithMethod(method string) RequestOption {
+	return func(c *callback) {
+		c.method = method
+	}
+}
+
+// WithTarget sets the url of the request, DefaultTarget by default.
+func WithTarget(target string) RequestOption {
+	return func(c *callback) {
+		c.target = target
+	}
+}
+
+// WithHeader adds a header to the request, e.g. the tenant header of a SecretStore.
+func WithHeader(name, value string) RequestOption {
+	return func(c *callback) {
+		c.header.Add(name, value)
+	}
+}
+
+// WithBadSignature signs the callback with a secret the receiver does not know.
+func WithBadSignature() RequestOption {
+	return func(c *callback) {
+		c.secret += "-bad"
+	}
+}
+
+// WithStaleTimestamp signs the callback age before the time of the signer clock,
+// e.g. to test the tolerance window of a receiver.
+func WithStaleTimestamp(age time.Duration) RequestOption {
+	return func(c *callback) {
+		c.signedAt = c.signedAt.Add(-age)
+	}
+}
+
+// WithoutHeader removes the header name after signing, e.g. callbackreceiver.SignatureHeader.
+func WithoutHeader(name string) RequestOption {
+	return func(c *callback) {
+		c.without = append(c.without, name)
+	}
+}
+
+// WithTamperedPayload sends body instead of the signed payload.
+func WithTamperedPayload(body []byte) RequestOption {
+	return func(c *callback) {
+		c.body = body
+	}
+}
+
+// Request returns a callback request with payload signed as the callback service does.
+// The payload is sent as is when it is a []byte or a string and JSON encoded otherwise.
+// It panics when the payload can not be encoded, as httptest.NewRequest does on invalid input.
+func (s *Signer) Request(payload interface{}, opts ...RequestOption) *http.Request {
+	body := encode(payload)
+	c := s.newCallback(opts)
+	header := s.sign(c, body)
+
+	if c.body != nil {
+		body = c.body
+	}
+
+	r := httptest.NewRequest(c.method, c.target, bytes.NewReader(body))
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	return r
+}
+
+// Header returns the headers of a callback with payload, to test receivers
+// that do not take an *http.Request.
+func (s *Signer) Header(payload []byte, opts ...RequestOption) http.Header {
+	return s.sign(s.newCallback(opts), payload)
+}
+
+func (s *Signer) newCallback(opts []RequestOption) *callback {
+	c := &callback{
+		method:     http.MethodPost,
+		target:     DefaultTarget,
+		secret:     s.secret,
+		signedAt:   s.now(),
+		eventID:    uuid.NewString(),
+		attempt:    1,
+		deliveryID: uuid.NewString(),
+		header:     http.Header{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (s *Signer) sign(c *callback, payload []byte) http.Header {
+	signed, err := callbackreceiver.Sign(c.secret, payload, callbackreceiver.SignOptions{
+		Time:       c.signedAt,
+		EventID:    c.eventID,
+		Versions:   s.versions,
+		KeyID:      s.keyID,
+		Ed25519Key: s.key,
+		Standard:   s.standard && c.eventID != "",
+	})
+	if err != nil {
+		panic(fmt.Sprintf("callbackreceivertest: %v", err))
+	}
+
+	h := c.header.Clone()
+	h.Set("Content-Type", "application/json")
+	for name, values := range signed {
+		h[name] = values
+	}
+	if c.eventID != "" {
+		h.Set(callbackreceiver.AttemptHeader, strconv.Itoa(c.attempt))
+		h.Set(callbackreceiver.DeliveryIDHeader, c.deliveryID)
+	}
+
+	for _, name := range c.without {
+		h.Del(name)
+	}
+	return h
+}
+
+func encode(payload interface{}) []byte {
+	switch p := payload.(type) {
+	case []byte:
+		return p
+	case string:
+		return []byte(p)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		panic(fmt.Sprintf("callbackreceivertest: invalid payload: %v", err))
+	}
+	return b
+}
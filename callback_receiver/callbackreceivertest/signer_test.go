@@ -0,0 +1,160 @@
+package callbackreceivertest_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
+	"dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver/callbackreceivertest"
+)
+
+const secret = "test webhook secret key"
+
+func clock() time.Time {
+	return time.Unix(1700000000, 0)
+}
+
+func TestSigner(t *testing.T) {
+	payload := map[string]interface{}{"event": "payment_success", "amount": 100.5}
+
+	handler := callbackreceiver.NewVerifier(secret, callbackreceiver.WithClock(clock)).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	signer := callbackreceivertest.NewSigner(secret, callbackreceivertest.WithClock(clock))
+
+	tests := []struct {
+		name       string
+		signer     *callbackreceivertest.Signer
+		opts       []callbackreceivertest.RequestOption
+		wantStatus int
+	}{
+		{
+			name:       "accept signed callback",
+			signer:     signer,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "accept legacy callback without event id",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithEventID("")},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "accept versioned signature",
+			signer:     callbackreceivertest.NewSigner(secret, callbackreceivertest.WithClock(clock), callbackreceivertest.WithVersions(callbackreceiver.SignatureV1, callbackreceiver.SignatureV2)),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject bad signature",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithBadSignature()},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "reject other secret",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithSecret("other secret")},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "reject stale timestamp",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithStaleTimestamp(time.Hour)},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "reject missing signature",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithoutHeader(callbackreceiver.SignatureHeader)},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "reject missing event id",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithoutHeader(callbackreceiver.EventIDHeader)},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "reject tampered payload",
+			signer:     signer,
+			opts:       []callbackreceivertest.RequestOption{callbackreceivertest.WithTamperedPayload([]byte(`{"event":"payment_success","amount":1000.5}`))},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := callbackreceivertest.Serve(handler, tt.signer.Request(payload, tt.opts...))
+			callbackreceivertest.AssertStatus(t, w, tt.wantStatus)
+		})
+	}
+}
+
+func TestSignerDeliveryHeaders(t *testing.T) {
+	var got *callbackreceiver.Webhook
+	handler := callbackreceiver.NewVerifier(secret, callbackreceiver.WithClock(clock)).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = callbackreceiver.FromContext(r.Context())
+			w.Header().Set("X-Event-ID", got.EventID)
+			_, _ = w.Write([]byte("processed " + got.DeliveryID))
+		}),
+	)
+
+	signer := callbackreceivertest.NewSigner(secret, callbackreceivertest.WithClock(clock))
+	w := callbackreceivertest.Serve(handler, signer.Request(`{"event":"payment_success"}`,
+		callbackreceivertest.WithEventID("evt_1"),
+		callbackreceivertest.WithAttempt(3),
+		callbackreceivertest.WithDeliveryID("dlv_3"),
+	))
+
+	callbackreceivertest.AssertAccepted(t, w)
+	callbackreceivertest.AssertHeader(t, w, "X-Event-ID", "evt_1")
+	callbackreceivertest.AssertBodyContains(t, w, "processed dlv_3")
+	if got == nil || got.Attempt != 3 || !got.Time.Equal(clock()) {
+		t.Errorf("expected attempt 3 signed at %v, but got %+v", clock(), got)
+	}
+}
+
+func TestSignerEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	verifier := callbackreceiver.NewVerifier("",
+		callbackreceiver.WithClock(clock),
+		callbackreceiver.WithPublicKeys(callbackreceiver.StaticKeySet{"key-1": pub}),
+	)
+	signer := callbackreceivertest.NewSigner(secret, callbackreceivertest.WithClock(clock), callbackreceivertest.WithEd25519Key("key-1", priv))
+
+	webhook, err := verifier.VerifyHeader(signer.Header([]byte(`{"event":"payment_success"}`)), strings.NewReader(`{"event":"payment_success"}`))
+	if err != nil || webhook.KeyID != "key-1" {
+		t.Errorf("expected callback verified with key-1, but got %+v, %v", webhook, err)
+	}
+}
+
+func TestSignerStandardWebhooks(t *testing.T) {
+	const standardSecret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	verifier := callbackreceiver.NewVerifier(standardSecret, callbackreceiver.WithClock(clock), callbackreceiver.WithStandardWebhooks())
+	signer := callbackreceivertest.NewSigner(standardSecret, callbackreceivertest.WithClock(clock), callbackreceivertest.WithStandardWebhooks())
+
+	payload := []byte(`{"event":"payment_success"}`)
+	header := signer.Header(payload, callbackreceivertest.WithEventID("msg_1"))
+	if header.Get(callbackreceiver.StandardIDHeader) != "msg_1" || !strings.HasPrefix(header.Get(callbackreceiver.StandardSignatureHeader), "v1,") {
+		t.Fatalf("expected Standard Webhooks headers, but got %v", header)
+	}
+
+	// the Standard Webhooks headers alone are enough
+	header.Del(callbackreceiver.SignatureHeader)
+	header.Del(callbackreceiver.TimestampHeader)
+	webhook, err := verifier.VerifyHeader(header, strings.NewReader(string(payload)))
+	if err != nil || webhook.EventID != "msg_1" {
+		t.Errorf("expected callback msg_1 verified, but got %+v, %v", webhook, err)
+	}
+}
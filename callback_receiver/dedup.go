@@ -0,0 +1,152 @@
+package callbackreceiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DedupState is the processing state of a callback in a DedupStore.
+type DedupState string
+
+const (
+	// DedupStateNew is the state of a callback the store has not seen.
+	DedupStateNew        DedupState = ""
+	DedupStateProcessing DedupState = "PROCESSING"
+	DedupStateSucceeded  DedupState = "SUCCEEDED"
+	DedupStateFailed     DedupState = "FAILED"
+)
+
+// claimable reports whether a callback in the state s must be processed.
+func (s DedupState) claimable() bool {
+	return s == DedupStateNew || s == DedupStateFailed
+}
+
+// ErrClaimLost is returned by DedupStore.Complete when the key was claimed again since,
+// e.g. after the lease of a slow handler expired, so its outcome is not recorded.
+var ErrClaimLost = errors.New("dedup claim lost")
+
+// DedupClaim identifies a claim of a key in a DedupStore.
+type DedupClaim int64
+
+// DedupStore records which callbacks were processed.
+type DedupStore interface {
+	// Claim marks key as processing unless it is already processing or succeeded
+	// and returns the state key had before. The caller processes the callback
+	// only when the returned state is DedupStateNew or DedupStateFailed, and then
+	// completes it with the returned claim.
+	Claim(ctx context.Context, key string) (DedupState, DedupClaim, error)
+	// Complete records the outcome of the claim of key, DedupStateSucceeded or DedupStateFailed.
+	// It returns ErrClaimLost without recording it when key was claimed again since.
+	Complete(ctx context.Context, key string, claim DedupClaim, state DedupState) error
+}
+
+// DuplicateRetryAfter is the Retry-After sent for a callback that is still being processed.
+const DuplicateRetryAfter = 5 * time.Second
+
+// Idempotent wraps next so each callback is processed once.
+// Callbacks are deduplicated on their event id, or on the hash of their payload
+// for callbacks without one. It is meant to run behind Verifier.Middleware,
+// which makes the event id trustworthy.
+//
+// A 2xx response of next is recorded as succeeded, anything else as failed.
+// Duplicates of a succeeded callback get a 200 without calling next, duplicates
+// of a failed one are processed again and duplicates of a callback still being
+// processed get a 503 with a Retry-After header.
+func Idempotent(store DedupStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := dedupKey(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		state, claim, err := store.Claim(r.Context(), key)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		switch state {
+		case DedupStateSucceeded:
+			w.WriteHeader(http.StatusOK)
+			return
+		case DedupStateProcessing:
+			RetryLater(w, DuplicateRetryAfter)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		outcome := DedupStateFailed
+		defer func() {
+			// the outcome is recorded even when the sender went away
+			_ = store.Complete(context.WithoutCancel(r.Context()), key, claim, outcome)
+		}()
+
+		next.ServeHTTP(rec, r)
+		if rec.status() >= 200 && rec.status() < 300 {
+			outcome = DedupStateSucceeded
+		}
+	})
+}
+
+// dedupKey returns the event id of the verified webhook of r,
+// or the hash of the payload when there is none.
+func dedupKey(r *http.Request) (string, error) {
+	webhook, ok := FromContext(r.Context())
+	if ok && webhook.EventID != "" {
+		return "event:" + webhook.EventID, nil
+	}
+
+	var payload []byte
+	if ok {
+		payload = webhook.Payload
+	} else {
+		var err error
+		payload, err = io.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(payload))
+	}
+
+	sum := sha256.Sum256(payload)
+	return "payload:" + hex.EncodeToString(sum[:]), nil
+}
+
+// statusRecorder records the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// status returns the recorded status, 200 when the handler wrote nothing.
+func (s *statusRecorder) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+	return s.code
+}
@@ -0,0 +1,115 @@
+package callbackreceiver
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryDedupStore is a DedupStore keeping the most recently used keys in memory for a TTL.
+// It only deduplicates callbacks received by the same process.
+type MemoryDedupStore struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	claims  DedupClaim
+}
+
+type memoryDedupEntry struct {
+	key       string
+	state     DedupState
+	claim     DedupClaim
+	expiresAt time.Time
+}
+
+// NewMemoryDedupStore returns a store forgetting keys ttl after their last update
+// and the least recently used keys beyond maxEntries. A zero maxEntries keeps every key.
+// A key still processing after ttl, e.g. after a handler hung, can be claimed again.
+func NewMemoryDedupStore(ttl time.Duration, maxEntries int) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (m *MemoryDedupStore) Claim(_ context.Context, key string) (DedupState, DedupClaim, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := DedupStateNew
+	if entry, ok := m.get(key); ok {
+		state = entry.state
+	}
+	if !state.claimable() {
+		return state, 0, nil
+	}
+
+	m.claims++
+	m.set(key, DedupStateProcessing).claim = m.claims
+	return state, m.claims, nil
+}
+
+// Complete also records the outcome of a key the store forgot since it was claimed.
+func (m *MemoryDedupStore) Complete(_ context.Context, key string, claim DedupClaim, state DedupState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.get(key); ok && (entry.state != DedupStateProcessing || entry.claim != claim) {
+		return ErrClaimLost
+	}
+	m.set(key, state).claim = claim
+	return nil
+}
+
+// Len returns the number of keys in the store, including the expired ones not evicted yet.
+func (m *MemoryDedupStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lru.Len()
+}
+
+// get returns the entry of key, evicting it when it expired.
+func (m *MemoryDedupStore) get(key string) (*memoryDedupEntry, bool) {
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*memoryDedupEntry)
+	if !m.now().Before(entry.expiresAt) {
+		m.lru.Remove(el)
+		delete(m.entries, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+// set sets the state of key and returns its entry.
+func (m *MemoryDedupStore) set(key string, state DedupState) *memoryDedupEntry {
+	expiresAt := m.now().Add(m.ttl)
+	if el, ok := m.entries[key]; ok {
+		entry := el.Value.(*memoryDedupEntry)
+		entry.state = state
+		entry.expiresAt = expiresAt
+		m.lru.MoveToFront(el)
+		return entry
+	}
+
+	entry := &memoryDedupEntry{key: key, state: state, expiresAt: expiresAt}
+	m.entries[key] = m.lru.PushFront(entry)
+	for m.maxEntries > 0 && m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryDedupEntry).key)
+	}
+	return entry
+}
@@ -0,0 +1,146 @@
+package callbackreceiver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLDedupStore is a DedupStore keeping the keys in a database table,
+// so replicas of a receiver share it. The table is created by CreateTable.
+type SQLDedupStore struct {
+	db     *sql.DB
+	table  string
+	lease  time.Duration
+	dollar bool
+	now    func() time.Time
+}
+
+type SQLDedupOption func(*SQLDedupStore)
+
+// WithLease sets how long a key stays processing before it can be claimed again,
+// e.g. after the receiver crashed while processing it. It is 5 minutes by default.
+func WithLease(d time.Duration) SQLDedupOption {
+	return func(s *SQLDedupStore) {
+		s.lease = d
+	}
+}
+
+// WithDollarPlaceholders makes the queries use $1 placeholders instead of ?, e.g. for PostgreSQL.
+func WithDollarPlaceholders() SQLDedupOption {
+	return func(s *SQLDedupStore) {
+		s.dollar = true
+	}
+}
+
+// NewSQLDedupStore returns a store using the table of db. The table name is
+// written into the queries as is and must not come from user input.
+func NewSQLDedupStore(db *sql.DB, table string, opts ...SQLDedupOption) *SQLDedupStore {
+	s := &SQLDedupStore{
+		db:    db,
+		table: table,
+		lease: 5 * time.Minute,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateTable creates the table of the store if it does not exist.
+func (s *SQLDedupStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (dedup_key VARCHAR(255) PRIMARY KEY, state VARCHAR(16) NOT NULL, updated_at BIGINT NOT NULL)",
+		s.table,
+	))
+	return err
+}
+
+// Claim reports a key whose lease expired as DedupStateFailed.
+// The claim is the time of the claim, which Complete checks the key was not claimed again since.
+func (s *SQLDedupStore) Claim(ctx context.Context, key string) (DedupState, DedupClaim, error) {
+	now := s.now()
+	claim := DedupClaim(now.UnixNano())
+
+	_, insertErr := s.db.ExecContext(ctx, s.query(
+		"INSERT INTO %s (dedup_key, state, updated_at) VALUES (?, ?, ?)"),
+		key, DedupStateProcessing, int64(claim),
+	)
+	if insertErr == nil {
+		return DedupStateNew, claim, nil
+	}
+
+	// the key exists, take it over when the last attempt failed or its lease expired
+	res, err := s.db.ExecContext(ctx, s.query(
+		"UPDATE %s SET state = ?, updated_at = ? WHERE dedup_key = ? AND (state = ? OR (state = ? AND updated_at < ?))"),
+		DedupStateProcessing, int64(claim), key, DedupStateFailed, DedupStateProcessing, now.Add(-s.lease).UnixNano(),
+	)
+	if err != nil {
+		return DedupStateNew, 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return DedupStateNew, 0, err
+	} else if n > 0 {
+		return DedupStateFailed, claim, nil
+	}
+
+	var state DedupState
+	err = s.db.QueryRowContext(ctx, s.query("SELECT state FROM %s WHERE dedup_key = ?"), key).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the insert failed for another reason than an existing key
+		return DedupStateNew, 0, insertErr
+	}
+	if err != nil {
+		return DedupStateNew, 0, err
+	}
+	return state, 0, nil
+}
+
+// Complete only updates the key while it is still processing since the claim.
+func (s *SQLDedupStore) Complete(ctx context.Context, key string, claim DedupClaim, state DedupState) error {
+	res, err := s.db.ExecContext(ctx, s.query(
+		"UPDATE %s SET state = ?, updated_at = ? WHERE dedup_key = ? AND state = ? AND updated_at = ?"),
+		state, s.now().UnixNano(), key, DedupStateProcessing, int64(claim),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
+
+// Purge deletes the keys last updated before t and returns how many were deleted.
+func (s *SQLDedupStore) Purge(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.query("DELETE FROM %s WHERE updated_at < ?"), t.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// query returns q with the table name and the placeholders of the store.
+func (s *SQLDedupStore) query(q string) string {
+	q = fmt.Sprintf(q, s.table)
+	if !s.dollar {
+		return q
+	}
+
+	var b []byte
+	n := 0
+	for i := 0; i < len(q); i++ {
+		if q[i] != '?' {
+			b = append(b, q[i])
+			continue
+		}
+		n++
+		b = append(b, fmt.Sprintf("$%d", n)...)
+	}
+	return string(b)
+}
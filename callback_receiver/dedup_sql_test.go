@@ -0,0 +1,216 @@
+package callbackreceiver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dedupDriver is a database/sql driver understanding the queries of SQLDedupStore.
+type dedupDriver struct {
+	mu   sync.Mutex
+	rows map[string]dedupRow
+}
+
+type dedupRow struct {
+	state     string
+	updatedAt int64
+}
+
+func (d *dedupDriver) Open(string) (driver.Conn, error) {
+	return &dedupConn{d: d}, nil
+}
+
+type dedupConn struct {
+	d *dedupDriver
+}
+
+func (c *dedupConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *dedupConn) Close() error {
+	return nil
+}
+
+func (c *dedupConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (c *dedupConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	arg := func(i int) driver.Value { return args[i].Value }
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "INSERT"):
+		key := arg(0).(string)
+		if _, ok := c.d.rows[key]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		c.d.rows[key] = dedupRow{state: arg(1).(string), updatedAt: arg(2).(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE") && strings.Contains(query, "AND ("):
+		key := arg(2).(string)
+		row, ok := c.d.rows[key]
+		if !ok || !(row.state == arg(3).(string) || (row.state == arg(4).(string) && row.updatedAt < arg(5).(int64))) {
+			return driver.RowsAffected(0), nil
+		}
+		c.d.rows[key] = dedupRow{state: arg(0).(string), updatedAt: arg(1).(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE"):
+		key := arg(2).(string)
+		row, ok := c.d.rows[key]
+		if !ok || row.state != arg(3).(string) || row.updatedAt != arg(4).(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		c.d.rows[key] = dedupRow{state: arg(0).(string), updatedAt: arg(1).(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "DELETE"):
+		var n int64
+		for key, row := range c.d.rows {
+			if row.updatedAt < arg(0).(int64) {
+				delete(c.d.rows, key)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return nil, errors.New("unexpected query " + query)
+}
+
+func (c *dedupConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	if !strings.HasPrefix(query, "SELECT state") {
+		return nil, errors.New("unexpected query " + query)
+	}
+	row, ok := c.d.rows[args[0].Value.(string)]
+	if !ok {
+		return &dedupRows{}, nil
+	}
+	return &dedupRows{values: []string{row.state}}, nil
+}
+
+type dedupRows struct {
+	values []string
+}
+
+func (r *dedupRows) Columns() []string {
+	return []string{"state"}
+}
+
+func (r *dedupRows) Close() error {
+	return nil
+}
+
+func (r *dedupRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+var testDedupDriver = &dedupDriver{rows: make(map[string]dedupRow)}
+
+func init() {
+	sql.Register("dedup", testDedupDriver)
+}
+
+func TestSQLDedupStore(t *testing.T) {
+	testDedupDriver.mu.Lock()
+	testDedupDriver.rows = make(map[string]dedupRow)
+	testDedupDriver.mu.Unlock()
+
+	ctx := context.Background()
+	db, err := sql.Open("dedup", "")
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	defer db.Close()
+
+	now := time.Unix(1700000000, 0)
+	store := NewSQLDedupStore(db, "callback_dedup", WithLease(time.Minute))
+	store.now = func() time.Time { return now }
+	if err := store.CreateTable(ctx); err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	claims := make(map[string]DedupClaim)
+	claim := func(key string) DedupState {
+		state, c, err := store.Claim(ctx, key)
+		if err != nil {
+			t.Fatalf("expected to get nil error, but got %v", err)
+		}
+		if state.claimable() {
+			claims[key] = c
+		}
+		return state
+	}
+	complete := func(key string, state DedupState) error {
+		now = now.Add(time.Second)
+		return store.Complete(ctx, key, claims[key], state)
+	}
+
+	if state := claim("a"); state != DedupStateNew {
+		t.Errorf("expected new key, but got %q", state)
+	}
+	if state := claim("a"); state != DedupStateProcessing {
+		t.Errorf("expected processing key, but got %q", state)
+	}
+
+	_ = complete("a", DedupStateFailed)
+	if state := claim("a"); state != DedupStateFailed {
+		t.Errorf("expected failed key to be claimed again, but got %q", state)
+	}
+
+	_ = complete("a", DedupStateSucceeded)
+	if state := claim("a"); state != DedupStateSucceeded {
+		t.Errorf("expected succeeded key, but got %q", state)
+	}
+
+	claim("b")
+	stale := claims["b"]
+	now = now.Add(2 * time.Minute)
+	if state := claim("b"); state != DedupStateFailed {
+		t.Errorf("expected key with expired lease to be claimed again, but got %q", state)
+	}
+
+	// the handler whose lease expired must not overwrite the outcome of the new claim
+	if err := store.Complete(ctx, "b", stale, DedupStateFailed); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("expected to get %v, but got %v", ErrClaimLost, err)
+	}
+	if err := complete("b", DedupStateSucceeded); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+	if err := store.Complete(ctx, "b", stale, DedupStateFailed); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("expected to get %v, but got %v", ErrClaimLost, err)
+	}
+	if state := claim("b"); state != DedupStateSucceeded {
+		t.Errorf("expected succeeded key, but got %q", state)
+	}
+
+	n, err := store.Purge(ctx, now.Add(-time.Minute))
+	if err != nil || n != 1 {
+		t.Errorf("expected to purge 1 key, but got %d, %v", n, err)
+	}
+}
+
+func TestSQLDedupStoreQuery(t *testing.T) {
+	store := NewSQLDedupStore(nil, "callback_dedup", WithDollarPlaceholders())
+	got := store.query("UPDATE %s SET state = ? WHERE dedup_key = ?")
+	want := "UPDATE callback_dedup SET state = $1 WHERE dedup_key = $2"
+	if got != want {
+		t.Errorf("expected query %q, but got %q", want, got)
+	}
+}
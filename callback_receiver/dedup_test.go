@@ -0,0 +1,191 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotent(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+
+	var calls atomic.Int32
+	status := http.StatusOK
+	handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(Idempotent(
+		NewMemoryDedupStore(time.Hour, 0),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(status)
+		}),
+	))
+
+	send := func(eventID, body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/callback", strings.NewReader(body))
+		r.Header.Set(TimestampHeader, timestamp)
+		if eventID != "" {
+			r.Header.Set(EventIDHeader, eventID)
+			r.Header.Set(SignatureHeader, signEvent(testSecret, timestamp, eventID, body))
+		} else {
+			r.Header.Set(SignatureHeader, sign(testSecret, timestamp, body))
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	status = http.StatusInternalServerError
+	if code := send(eventID, payload); code != http.StatusInternalServerError || calls.Load() != 1 {
+		t.Errorf("expected failed first attempt to be processed, but got status %d after %d calls", code, calls.Load())
+	}
+
+	status = http.StatusOK
+	if code := send(eventID, payload); code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("expected duplicate of failed attempt to be processed, but got status %d after %d calls", code, calls.Load())
+	}
+
+	if code := send(eventID, payload); code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("expected duplicate of succeeded attempt to be acknowledged, but got status %d after %d calls", code, calls.Load())
+	}
+
+	if code := send("", payload); code != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("expected callback without event id to be processed, but got status %d after %d calls", code, calls.Load())
+	}
+
+	if code := send("", payload); code != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("expected duplicate payload to be acknowledged, but got status %d after %d calls", code, calls.Load())
+	}
+}
+
+func TestIdempotentProcessing(t *testing.T) {
+	store := NewMemoryDedupStore(time.Hour, 0)
+	if _, _, err := store.Claim(context.Background(), "event:in-flight"); err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	handler := Idempotent(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected in-flight duplicate not to be processed")
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/callback", nil)
+	r = r.WithContext(NewContext(r.Context(), &Webhook{EventID: "in-flight"}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected Retry-After header")
+	}
+}
+
+func TestIdempotentPanic(t *testing.T) {
+	store := NewMemoryDedupStore(time.Hour, 0)
+	handler := Idempotent(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/callback", nil)
+	r = r.WithContext(NewContext(r.Context(), &Webhook{EventID: "panicking"}))
+	func() {
+		defer func() { _ = recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+
+	state, _, _ := store.Claim(context.Background(), "event:panicking")
+	if state != DedupStateFailed {
+		t.Errorf("expected state %s after panic, but got %q", DedupStateFailed, state)
+	}
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryDedupStore(time.Minute, 2)
+	store.now = func() time.Time { return now }
+
+	claims := make(map[string]DedupClaim)
+	claim := func(key string) DedupState {
+		state, c, err := store.Claim(ctx, key)
+		if err != nil {
+			t.Fatalf("expected to get nil error, but got %v", err)
+		}
+		if state.claimable() {
+			claims[key] = c
+		}
+		return state
+	}
+
+	if state := claim("a"); state != DedupStateNew {
+		t.Errorf("expected new key, but got %q", state)
+	}
+	if state := claim("a"); state != DedupStateProcessing {
+		t.Errorf("expected processing key, but got %q", state)
+	}
+
+	_ = store.Complete(ctx, "a", claims["a"], DedupStateSucceeded)
+	if state := claim("a"); state != DedupStateSucceeded {
+		t.Errorf("expected succeeded key, but got %q", state)
+	}
+
+	// b and c evict a, the least recently used key
+	claim("b")
+	claim("c")
+	if store.Len() != 2 {
+		t.Errorf("expected 2 keys, but got %d", store.Len())
+	}
+	if state := claim("a"); state != DedupStateNew {
+		t.Errorf("expected evicted key to be new, but got %q", state)
+	}
+
+	now = now.Add(time.Minute)
+	if state := claim("c"); state != DedupStateNew {
+		t.Errorf("expected expired key to be new, but got %q", state)
+	}
+}
+
+func TestMemoryDedupStoreClaimLost(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryDedupStore(time.Minute, 0)
+	store.now = func() time.Time { return now }
+
+	_, stale, _ := store.Claim(ctx, "a")
+	now = now.Add(time.Minute)
+	state, current, _ := store.Claim(ctx, "a")
+	if state != DedupStateNew || current == stale {
+		t.Fatalf("expected key still processing after the ttl to be claimed again, but got %q", state)
+	}
+
+	// the handler that outlasted the ttl must not overwrite the outcome of the new claim
+	if err := store.Complete(ctx, "a", stale, DedupStateFailed); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("expected to get %v, but got %v", ErrClaimLost, err)
+	}
+	if err := store.Complete(ctx, "a", current, DedupStateSucceeded); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+	if err := store.Complete(ctx, "a", stale, DedupStateFailed); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("expected to get %v, but got %v", ErrClaimLost, err)
+	}
+	if state, _, _ := store.Claim(ctx, "a"); state != DedupStateSucceeded {
+		t.Errorf("expected succeeded key, but got %q", state)
+	}
+
+	// the outcome of a key forgotten since its claim is still recorded
+	_, c, _ := store.Claim(ctx, "b")
+	now = now.Add(time.Minute)
+	if err := store.Complete(ctx, "b", c, DedupStateSucceeded); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+	}
+	if state, _, _ := store.Claim(ctx, "b"); state != DedupStateSucceeded {
+		t.Errorf("expected succeeded key, but got %q", state)
+	}
+}
@@ -0,0 +1,65 @@
+// Package callbackreceiver verifies the callbacks sent by the callback service.
+//
+// Each callback is sent to the callback url of its event with the JSON payload as body
+// and the following headers:
+//
+//	X-MP-SIGNATURE    HMAC signatures of the signed material with the webhook secret
+//	X-MP-Time         Unix time in seconds the callback was signed at
+//	X-MP-Event-ID     id of the event, the same for every attempt
+//	X-MP-Attempt      delivery attempt number of the event, starting at 1
+//	X-MP-Delivery-ID  id of the delivery attempt, as in the callback history
+//
+// The signed material is
+//
+//	<X-MP-Time>.<X-MP-Event-ID>.<body>
+//
+// so neither the time nor the event id can be changed without invalidating the signature.
+// Callbacks of older senders carry no X-MP-Event-ID and are signed as <X-MP-Time>.<body>.
+// X-MP-Attempt and X-MP-Delivery-ID are not signed.
+//
+// The event id changed the signed material: current senders always send X-MP-Event-ID, so
+// VerifyRequestHash, which only verifies the legacy material, rejects their callbacks with
+// ErrSignatureMismatch. Receivers calling it must pass the X-MP-Event-ID header to VerifyEventHash,
+// or use a Verifier, which reads the header itself and accepts both materials.
+//
+// X-MP-SIGNATURE is versioned, so the algorithm can change without breaking receivers:
+//
+//	t=1700000000,v1=<hex HMAC-SHA256>,v2=<hex HMAC-SHA512>
+//
+// It may carry several signatures, of the same or different versions, and receivers ignore
+// the versions they do not know. A Verifier checks the strongest version it accepts.
+// The legacy header is a bare hex HMAC-SHA256 and is accepted as v1 unless disabled
+// with WithLegacySignatures.
+//
+// Senders holding an Ed25519 key also sign the same material with it, so receivers
+// verify callbacks with a public key instead of a secret they could forge callbacks with:
+//
+//	X-MP-Signature-Ed25519  base64url Ed25519 signature of the signed material
+//	X-MP-Key-ID             id of the key in the key set of the sender
+//
+// The public keys are published as a JWK set of OKP Ed25519 keys (RFC 8037),
+// which a RemoteKeySet loads and caches.
+//
+// For frameworks verifying the Standard Webhooks specification, senders can sign callbacks
+// as the specification does instead, with the webhook-id, webhook-timestamp and
+// webhook-signature headers and "whsec_" secrets. A Verifier accepts them with WithStandardWebhooks.
+//
+// Sign computes all of these signature headers. The mock client and the Signer of callbackreceivertest
+// sign their callbacks with it, so they always send the material a Verifier expects.
+//
+// A Verifier computes the signatures while reading the body, up to its maximum body size,
+// and its Middleware passes the payload to the handler only once it was verified.
+//
+// Receivers deduplicate callbacks on the event id, which is available in Webhook.EventID
+// once a Verifier accepted the callback.
+//
+// The response status controls the retries of the callback: 2xx acknowledges it, 410 unsubscribes
+// the callback url, any other 4xx fails it permanently and 429 or 503 ask for a retry after the
+// Retry-After header. Other failures are retried with the retry policy of the event.
+// Acknowledge, Unsubscribe, RejectPermanently, RetryLater and SlowDown write these responses.
+// Verification failures a later attempt may pass, e.g. a stale timestamp or a key set not yet refreshed,
+// are answered with a 503 since every attempt is signed again; only a signature that does not match is a 401.
+//
+// Receivers whose handlers may outlast the timeout of the sender use an AsyncReceiver,
+// which acknowledges callbacks with a 202 and handles them on a bounded worker pool.
+package callbackreceiver
@@ -0,0 +1,214 @@
+package callbackreceiver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when the key id of an Ed25519 signature is not in the key set.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// PublicKeyStore looks up the Ed25519 public keys callbacks are signed with.
+type PublicKeyStore interface {
+	// PublicKey returns the key of id, or ErrUnknownKey when there is none.
+	PublicKey(ctx context.Context, id string) (ed25519.PublicKey, error)
+}
+
+// StaticKeySet is a PublicKeyStore holding the public keys by id in memory.
+type StaticKeySet map[string]ed25519.PublicKey
+
+func (s StaticKeySet) PublicKey(_ context.Context, id string) (ed25519.PublicKey, error) {
+	key, ok := s[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JSONWebKey is an Ed25519 public key in the JWK format of RFC 8037.
+type JSONWebKey struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	// X is the base64url public key
+	X     string `json:"x"`
+	KeyID string `json:"kid"`
+	Use   string `json:"use,omitempty"`
+	Alg   string `json:"alg,omitempty"`
+}
+
+// NewJSONWebKey returns the JWK of the public key of id.
+func NewJSONWebKey(id string, key ed25519.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       base64.RawURLEncoding.EncodeToString(key),
+		KeyID:   id,
+		Use:     "sig",
+		Alg:     "EdDSA",
+	}
+}
+
+// PublicKey returns the Ed25519 public key of the JWK.
+func (k JSONWebKey) PublicKey() (ed25519.PublicKey, error) {
+	if k.KeyType != "OKP" || k.Curve != "Ed25519" {
+		return nil, fmt.Errorf("unsupported key type %s %s", k.KeyType, k.Curve)
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 key %s", k.KeyID)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// JSONWebKeySet is the key set document published by the sender, {"keys":[...]}.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// KeySet returns the Ed25519 keys of the document by id, skipping the keys of other types.
+func (s JSONWebKeySet) KeySet() (StaticKeySet, error) {
+	keys := make(StaticKeySet, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.KeyType != "OKP" || k.Curve != "Ed25519" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		key, err := k.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[k.KeyID] = key
+	}
+	return keys, nil
+}
+
+// RemoteKeySet is a PublicKeyStore loading a key set document from a url and caching it.
+// The document is loaded again after the TTL, or when a key id is missing from it,
+// at most once per MinRefreshInterval so unknown key ids can not flood the sender.
+// A single load runs at a time, detached from the requests waiting for it, and lookups
+// of cached keys do not wait for it.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        StaticKeySet
+	refreshedAt time.Time
+	// loading is closed when the load in flight is done, nil without one
+	loading chan struct{}
+	// err is the error of the last load
+	err error
+}
+
+const (
+	// MinRefreshInterval is the least time between two loads of a RemoteKeySet.
+	MinRefreshInterval = 10 * time.Second
+	// KeySetLoadTimeout bounds a load of the key set document of a RemoteKeySet.
+	KeySetLoadTimeout = 10 * time.Second
+	// MaxKeySetSize is the maximum size of the key set document of a RemoteKeySet.
+	MaxKeySetSize = 1 << 20
+)
+
+// NewRemoteKeySet returns a key set loaded from url with client and cached for ttl.
+// A nil client uses http.DefaultClient.
+func NewRemoteKeySet(url string, client *http.Client, ttl time.Duration) *RemoteKeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RemoteKeySet{
+		url:    url,
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+func (r *RemoteKeySet) PublicKey(ctx context.Context, id string) (ed25519.PublicKey, error) {
+	r.mu.Lock()
+	now := r.now()
+	stale := r.keys == nil || now.Sub(r.refreshedAt) >= r.ttl
+	_, ok := r.keys[id]
+	if (!ok || stale) && r.loading == nil && now.Sub(r.refreshedAt) >= MinRefreshInterval {
+		r.refreshedAt = now
+		r.loading = make(chan struct{})
+		go r.refresh(r.loading)
+	}
+	loading := r.loading
+	r.mu.Unlock()
+
+	if loading != nil && (!ok || stale) {
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrSecretStore, ctx.Err())
+		}
+	}
+
+	r.mu.Lock()
+	keys, err := r.keys, r.err
+	r.mu.Unlock()
+
+	// the cached keys are used while the document can not be loaded
+	if keys == nil && err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSecretStore, err)
+	}
+	return keys.PublicKey(ctx, id)
+}
+
+// refresh loads the key set document and closes done, keeping the cached keys when it fails.
+func (r *RemoteKeySet) refresh(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), KeySetLoadTimeout)
+	defer cancel()
+
+	keys, err := r.load(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.keys = keys
+	}
+	r.err = err
+	r.loading = nil
+	close(done)
+}
+
+// load loads the key set document.
+func (r *RemoteKeySet) load(ctx context.Context) (StaticKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key set: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to load key set: status %d", res.StatusCode)
+	}
+
+	var doc JSONWebKeySet
+	if err := json.NewDecoder(io.LimitReader(res.Body, MaxKeySetSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	keys, err := doc.KeySet()
+	if err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+	return keys, nil
+}
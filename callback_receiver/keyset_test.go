@@ -0,0 +1,333 @@
+package callbackreceiver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	return pub, priv
+}
+
+func signEd25519(key ed25519.PrivateKey, timestamp, eventID, payload string) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, signedMaterial(timestamp, eventID, []byte(payload))))
+}
+
+func TestVerifyEd25519(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+	pub, priv := newTestKey(t)
+	_, otherPriv := newTestKey(t)
+	keys := StaticKeySet{"key-1": pub}
+
+	tests := []struct {
+		name      string
+		secret    string
+		keys      PublicKeyStore
+		header    map[string]string
+		wantErr   error
+		wantKeyID string
+	}{
+		{
+			name: "accept ed25519 signature",
+			keys: keys,
+			header: map[string]string{
+				Ed25519SignatureHeader: signEd25519(priv, timestamp, eventID, payload),
+				KeyIDHeader:            "key-1",
+			},
+			wantKeyID: "key-1",
+		},
+		{
+			name:   "prefer ed25519 over hmac signature",
+			secret: testSecret,
+			keys:   keys,
+			header: map[string]string{
+				SignatureHeader:        signEvent("other secret", timestamp, eventID, payload),
+				Ed25519SignatureHeader: signEd25519(priv, timestamp, eventID, payload),
+				KeyIDHeader:            "key-1",
+			},
+			wantKeyID: "key-1",
+		},
+		{
+			name:   "fall back to hmac signature",
+			secret: testSecret,
+			keys:   keys,
+			header: map[string]string{
+				SignatureHeader: signEvent(testSecret, timestamp, eventID, payload),
+			},
+		},
+		{
+			name:   "ignore ed25519 signature without keys",
+			secret: testSecret,
+			header: map[string]string{
+				SignatureHeader:        signEvent(testSecret, timestamp, eventID, payload),
+				Ed25519SignatureHeader: "invalid",
+				KeyIDHeader:            "key-1",
+			},
+		},
+		{
+			name: "reject hmac signature without secret",
+			keys: keys,
+			header: map[string]string{
+				SignatureHeader: signEvent(testSecret, timestamp, eventID, payload),
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "reject signature of other key",
+			keys: keys,
+			header: map[string]string{
+				Ed25519SignatureHeader: signEd25519(otherPriv, timestamp, eventID, payload),
+				KeyIDHeader:            "key-1",
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "reject tampered event id",
+			keys: keys,
+			header: map[string]string{
+				Ed25519SignatureHeader: signEd25519(priv, timestamp, "other-event", payload),
+				KeyIDHeader:            "key-1",
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "reject malformed signature",
+			keys: keys,
+			header: map[string]string{
+				Ed25519SignatureHeader: "not base64!",
+				KeyIDHeader:            "key-1",
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "reject unknown key",
+			keys: keys,
+			header: map[string]string{
+				Ed25519SignatureHeader: signEd25519(priv, timestamp, eventID, payload),
+				KeyIDHeader:            "key-2",
+			},
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name: "reject missing key id",
+			keys: keys,
+			header: map[string]string{
+				Ed25519SignatureHeader: signEd25519(priv, timestamp, eventID, payload),
+			},
+			wantErr: ErrUnknownKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []VerifierOption{WithClock(testClock)}
+			if tt.keys != nil {
+				opts = append(opts, WithPublicKeys(tt.keys))
+			}
+
+			h := http.Header{}
+			h.Set(TimestampHeader, timestamp)
+			h.Set(EventIDHeader, eventID)
+			for name, value := range tt.header {
+				h.Set(name, value)
+			}
+
+			webhook, err := NewVerifier(tt.secret, opts...).VerifyHeader(h, strings.NewReader(payload))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && webhook.KeyID != tt.wantKeyID {
+				t.Errorf("expected key id %q, but got %q", tt.wantKeyID, webhook.KeyID)
+			}
+		})
+	}
+}
+
+func TestJSONWebKeySet(t *testing.T) {
+	pub, _ := newTestKey(t)
+
+	doc, err := json.Marshal(JSONWebKeySet{Keys: []JSONWebKey{
+		NewJSONWebKey("key-1", pub),
+		{KeyType: "RSA", KeyID: "rsa-1"},
+	}})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	var set JSONWebKeySet
+	if err := json.Unmarshal(doc, &set); err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	keys, err := set.KeySet()
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	if len(keys) != 1 || !keys["key-1"].Equal(pub) {
+		t.Errorf("expected only the Ed25519 key, but got %v", keys)
+	}
+
+	invalid := NewJSONWebKey("key-2", pub)
+	invalid.X = invalid.X[:10]
+	if _, err := (JSONWebKeySet{Keys: []JSONWebKey{invalid}}).KeySet(); err == nil {
+		t.Errorf("expected error for truncated key")
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	pub1, _ := newTestKey(t)
+	pub2, _ := newTestKey(t)
+
+	var loads atomic.Int32
+	published := []JSONWebKey{NewJSONWebKey("key-1", pub1)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loads.Add(1)
+		_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: published})
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	keys := NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+	keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if key, err := keys.PublicKey(ctx, "key-1"); err != nil || !key.Equal(pub1) {
+		t.Errorf("expected key-1, but got %v, %v", key, err)
+	}
+	if _, err := keys.PublicKey(ctx, "key-1"); err != nil || loads.Load() != 1 {
+		t.Errorf("expected cached key set, but got %d loads, %v", loads.Load(), err)
+	}
+
+	// a rotated key is loaded on its first use, at most once per MinRefreshInterval
+	published = append(published, NewJSONWebKey("key-2", pub2))
+	if _, err := keys.PublicKey(ctx, "key-2"); !errors.Is(err, ErrUnknownKey) || loads.Load() != 1 {
+		t.Errorf("expected no reload within the refresh interval, but got %d loads, %v", loads.Load(), err)
+	}
+
+	now = now.Add(MinRefreshInterval)
+	if key, err := keys.PublicKey(ctx, "key-2"); err != nil || !key.Equal(pub2) || loads.Load() != 2 {
+		t.Errorf("expected key-2 after reload, but got %d loads, %v", loads.Load(), err)
+	}
+}
+
+func TestRemoteKeySetUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := NewRemoteKeySet(server.URL, server.Client(), time.Hour).PublicKey(context.Background(), "key-1")
+	if !errors.Is(err, ErrSecretStore) {
+		t.Errorf("expected to get %v, but got %v", ErrSecretStore, err)
+	}
+}
+
+func TestRemoteKeySetSingleLoad(t *testing.T) {
+	pub1, _ := newTestKey(t)
+	pub2, _ := newTestKey(t)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loads.Add(1) == 1 {
+			_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: []JSONWebKey{NewJSONWebKey("key-1", pub1)}})
+			return
+		}
+		<-release
+		_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: []JSONWebKey{NewJSONWebKey("key-1", pub1), NewJSONWebKey("key-2", pub2)}})
+	}))
+	defer server.Close()
+
+	var now atomic.Int64
+	now.Store(1700000000)
+	keys := NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+	keys.now = func() time.Time { return time.Unix(now.Load(), 0) }
+	ctx := context.Background()
+
+	if _, err := keys.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	now.Add(int64(MinRefreshInterval / time.Second))
+
+	// every lookup of the rotated key waits for the same load
+	errs := make(chan error, 5)
+	for range 5 {
+		go func() {
+			key, err := keys.PublicKey(ctx, "key-2")
+			if err == nil && !key.Equal(pub2) {
+				err = errors.New("unexpected key")
+			}
+			errs <- err
+		}()
+	}
+	for loads.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a cached key is returned while the load is in flight
+	done := make(chan error, 1)
+	go func() {
+		_, err := keys.PublicKey(ctx, "key-1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected to get nil error, but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected cached key lookup not to wait for the load")
+	}
+
+	// a lookup gives up with its context, the load goes on
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := keys.PublicKey(canceled, "key-2"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected to get %v, but got %v", context.Canceled, err)
+	}
+
+	close(release)
+	for range 5 {
+		if err := <-errs; err != nil {
+			t.Errorf("expected key-2 after reload, but got %v", err)
+		}
+	}
+	if loads.Load() != 2 {
+		t.Errorf("expected 2 loads, but got %d", loads.Load())
+	}
+}
+
+func TestRemoteKeySetTooLarge(t *testing.T) {
+	pub, _ := newTestKey(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := []JSONWebKey{NewJSONWebKey("key-1", pub)}
+		for len(keys)*100 < MaxKeySetSize {
+			keys = append(keys, NewJSONWebKey("padding", pub))
+		}
+		_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: keys})
+	}))
+	defer server.Close()
+
+	_, err := NewRemoteKeySet(server.URL, server.Client(), time.Hour).PublicKey(context.Background(), "key-1")
+	if !errors.Is(err, ErrSecretStore) || !strings.Contains(err.Error(), "invalid key set") {
+		t.Errorf("expected to get %v for an oversized key set, but got %v", ErrSecretStore, err)
+	}
+}
@@ -0,0 +1,87 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// ErrorHandler writes the response of a request that failed verification.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler responds with StatusForError and its status text, or the message of ErrNotVerified,
+// with the Retry-After header of an HTTPError asking the sender to retry later.
+func DefaultErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	status := StatusForError(err)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		setRetryAfter(w, httpErr.RetryAfter)
+	}
+	msg := http.StatusText(status)
+	if errors.Is(err, ErrNotVerified) {
+		// a mistake in the setup of the receiver, not a detail of the callback
+		msg = ErrNotVerified.Error()
+	}
+	http.Error(w, msg, status)
+}
+
+// ErrNotVerified is returned by handlers meant to run behind Verifier.Middleware
+// when the request carries no verified webhook, i.e. the middleware is missing.
+var ErrNotVerified = errors.New("webhook not verified, the handler must run behind Verifier.Middleware")
+
+// StatusForError returns the HTTP status for a verification or routing error:
+// 401 for a signature that does not match or is of an unsupported version or an unknown tenant,
+// 503 for a callback that may verify on a later attempt, which is signed again by the sender:
+// a stale timestamp, an unknown signing key or missing or malformed signature, timestamp or tenant headers,
+// 422 for an event without handler, 500 for a failing secret store or event handler and a missing verification
+// and 400 for an invalid payload. An HTTPError is responded with its code.
+//
+// Only the 503 and 500 ask the sender to retry, any other 4xx fails the callback for good.
+func StatusForError(err error) int {
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.Code
+	case errors.Is(err, ErrSignatureMismatch), errors.Is(err, ErrUnsupportedSignature), errors.Is(err, ErrUnknownTenant):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrTimestampOutOfWindow), errors.Is(err, ErrUnknownKey), errors.Is(err, ErrMissingTenant),
+		errors.Is(err, ErrMissingHeaders), errors.Is(err, ErrInvalidTimestamp), errors.Is(err, ErrMalformedSignature),
+		errors.Is(err, ErrInvalidAttempt):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrSecretStore), errors.Is(err, ErrHandlerFailed), errors.Is(err, ErrNotVerified):
+		return http.StatusInternalServerError
+	case errors.Is(err, ErrUnknownEvent):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrPayloadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Middleware verifies each request before passing it to next.
+// The verified webhook is available to next through FromContext and the request body.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhook, err := v.VerifyRequest(r)
+		if err != nil {
+			v.errorHandler(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), webhook)))
+	})
+}
+
+// NewContext returns a copy of ctx carrying the verified webhook.
+func NewContext(ctx context.Context, webhook *Webhook) context.Context {
+	return context.WithValue(ctx, contextKey{}, webhook)
+}
+
+// FromContext returns the verified webhook stored by the middleware.
+func FromContext(ctx context.Context) (*Webhook, bool) {
+	webhook, ok := ctx.Value(contextKey{}).(*Webhook)
+	return webhook, ok
+}
@@ -0,0 +1,164 @@
+package callbackreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test webhook secret key"
+
+// testClock is the clock of the test verifiers, at the X-MP-Time 1700000000.
+func testClock() time.Time {
+	return time.Unix(1700000000, 0)
+}
+
+func sign(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestMiddleware(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+
+	tests := []struct {
+		name       string
+		signature  string
+		timestamp  string
+		body       string
+		opts       []VerifierOption
+		wantStatus int
+	}{
+		{
+			name:       "pass verified webhook to handler",
+			signature:  sign(testSecret, "1700000000", payload),
+			timestamp:  "1700000000",
+			body:       payload,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject wrong signature",
+			signature:  sign("other secret", "1700000000", payload),
+			timestamp:  "1700000000",
+			body:       payload,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "reject stale timestamp",
+			signature:  sign(testSecret, "1699999000", payload),
+			timestamp:  "1699999000",
+			body:       payload,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "accept stale timestamp without tolerance",
+			signature:  sign(testSecret, "1699999000", payload),
+			timestamp:  "1699999000",
+			body:       payload,
+			opts:       []VerifierOption{WithTolerance(0)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject invalid timestamp",
+			signature:  sign(testSecret, "yesterday", payload),
+			timestamp:  "yesterday",
+			body:       payload,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "reject missing headers",
+			body:       payload,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "reject body over the limit",
+			signature:  sign(testSecret, "1700000000", payload),
+			timestamp:  "1700000000",
+			body:       payload,
+			opts:       []VerifierOption{WithMaxBodySize(8)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:      "use custom error handler",
+			timestamp: "1700000000",
+			body:      payload,
+			opts: []VerifierOption{WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(http.StatusForbidden)
+			})},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]VerifierOption{WithClock(testClock)}, tt.opts...)
+			handler := NewVerifier(testSecret, opts...).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				webhook, ok := FromContext(r.Context())
+				if !ok || string(webhook.Payload) != tt.body || webhook.Timestamp != tt.timestamp {
+					t.Errorf("expected verified webhook in context, but got %+v", webhook)
+				}
+
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.body {
+					t.Errorf("expected body %s, but got %s", tt.body, body)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/callback", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				r.Header.Set(SignatureHeader, tt.signature)
+			}
+			if tt.timestamp != "" {
+				r.Header.Set(TimestampHeader, tt.timestamp)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: ErrSignatureMismatch, want: http.StatusUnauthorized},
+		{err: ErrUnsupportedSignature, want: http.StatusUnauthorized},
+		{err: ErrUnknownTenant, want: http.StatusUnauthorized},
+		{err: ErrTimestampOutOfWindow, want: http.StatusServiceUnavailable},
+		{err: ErrUnknownKey, want: http.StatusServiceUnavailable},
+		{err: ErrMissingTenant, want: http.StatusServiceUnavailable},
+		{err: ErrMissingHeaders, want: http.StatusServiceUnavailable},
+		{err: ErrInvalidTimestamp, want: http.StatusServiceUnavailable},
+		{err: ErrMalformedSignature, want: http.StatusServiceUnavailable},
+		{err: ErrInvalidAttempt, want: http.StatusServiceUnavailable},
+		{err: ErrSecretStore, want: http.StatusInternalServerError},
+		{err: ErrHandlerFailed, want: http.StatusInternalServerError},
+		{err: ErrNotVerified, want: http.StatusInternalServerError},
+		{err: ErrUnknownEvent, want: http.StatusUnprocessableEntity},
+		{err: &PayloadTooLargeError{Limit: 8}, want: http.StatusRequestEntityTooLarge},
+		{err: ErrInvalidPayload, want: http.StatusBadRequest},
+		{err: &HTTPError{Code: http.StatusGone, Err: ErrTimestampOutOfWindow}, want: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := StatusForError(tt.err); got != tt.want {
+				t.Errorf("expected to get %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package callbackreceiver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The callback service decides what to do with a callback from the status the receiver responds with:
+//
+//	2xx      acknowledged, the callback is not sent again
+//	410      the receiver unsubscribed, the callback fails and no callback is sent to its url anymore
+//	429/503  retry later, after the Retry-After header when present and the retry policy otherwise
+//	4xx      permanent failure, the callback is not retried
+//	5xx      temporary failure, the callback is retried with its retry policy
+//
+// The helpers below write these responses from a handler, the error constructors return them
+// from the event handlers of a Router.
+
+// Acknowledge responds 200, the callback was handled.
+func Acknowledge(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Unsubscribe responds 410, the receiver does not want any further callback at this url.
+func Unsubscribe(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+}
+
+// RejectPermanently responds 422 with reason, the callback will never be accepted and must not be retried.
+func RejectPermanently(w http.ResponseWriter, reason string) {
+	if reason == "" {
+		reason = http.StatusText(http.StatusUnprocessableEntity)
+	}
+	http.Error(w, reason, http.StatusUnprocessableEntity)
+}
+
+// RetryLater responds 503, asking the sender to retry the callback after d.
+// Without a positive d the sender falls back to its retry policy.
+func RetryLater(w http.ResponseWriter, d time.Duration) {
+	setRetryAfter(w, d)
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// SlowDown responds 429, asking the sender to send callbacks less often and to retry this one after d.
+func SlowDown(w http.ResponseWriter, d time.Duration) {
+	setRetryAfter(w, d)
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// PermanentError returns the error of an event handler that will never accept the event, responded with 422.
+func PermanentError(err error) error {
+	return &HTTPError{Code: http.StatusUnprocessableEntity, Err: err}
+}
+
+// UnsubscribeError returns the error of an event handler that wants no further callback, responded with 410.
+func UnsubscribeError(err error) error {
+	return &HTTPError{Code: http.StatusGone, Err: err}
+}
+
+// RetryLaterError returns the error of an event handler that cannot handle the event yet,
+// responded with 503 and a Retry-After of d.
+func RetryLaterError(d time.Duration, err error) error {
+	return &HTTPError{Code: http.StatusServiceUnavailable, Err: err, RetryAfter: d}
+}
+
+// setRetryAfter sets the Retry-After header to d in seconds, rounded up.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	seconds := int64((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
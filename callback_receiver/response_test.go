@@ -0,0 +1,119 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponses(t *testing.T) {
+	tests := []struct {
+		name           string
+		respond        func(w http.ResponseWriter)
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "acknowledge",
+			respond:    Acknowledge,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unsubscribe",
+			respond:    Unsubscribe,
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "reject permanently",
+			respond:    func(w http.ResponseWriter) { RejectPermanently(w, "unknown merchant") },
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "retry later",
+			respond:        func(w http.ResponseWriter) { RetryLater(w, 10*time.Minute) },
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "600",
+		},
+		{
+			name:           "round retry after up to seconds",
+			respond:        func(w http.ResponseWriter) { SlowDown(w, 1500*time.Millisecond) },
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+		{
+			name:       "retry later with retry policy",
+			respond:    func(w http.ResponseWriter) { RetryLater(w, 0) },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.respond(rec)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected to get %v, but got %v", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("expected Retry-After %q, but got %q", tt.wantRetryAfter, got)
+			}
+		})
+	}
+}
+
+func TestRouterResponseErrors(t *testing.T) {
+	router := NewRouter()
+	Handle(router, "payment_unknown", func(ctx context.Context, webhook *Webhook, payload map[string]interface{}) error {
+		return PermanentError(errors.New("unknown payment"))
+	})
+	Handle(router, "merchant_closed", func(ctx context.Context, webhook *Webhook, payload map[string]interface{}) error {
+		return UnsubscribeError(errors.New("merchant closed"))
+	})
+	Handle(router, "ledger_locked", func(ctx context.Context, webhook *Webhook, payload map[string]interface{}) error {
+		return RetryLaterError(time.Minute, errors.New("ledger locked"))
+	})
+
+	tests := []struct {
+		name           string
+		payload        string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "respond permanent error",
+			payload:    `{"event":"payment_unknown"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "respond unsubscribe error",
+			payload:    `{"event":"merchant_closed"}`,
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:           "respond retry later error",
+			payload:        `{"event":"ledger_locked"}`,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "60",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/callback", nil)
+			r = r.WithContext(NewContext(r.Context(), &Webhook{Payload: []byte(tt.payload)}))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected to get %v, but got %v", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("expected Retry-After %q, but got %q", tt.wantRetryAfter, got)
+			}
+		})
+	}
+}
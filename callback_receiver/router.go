@@ -0,0 +1,162 @@
+package callbackreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultEventKey is the payload field holding the event name, as in {"event":"payment_success"}.
+const DefaultEventKey = "event"
+
+var (
+	// ErrInvalidPayload is returned when the payload is not a JSON object with an event name
+	// or can not be decoded into the type of its handler.
+	ErrInvalidPayload = errors.New("invalid payload")
+	// ErrUnknownEvent is returned when no handler is registered for an event and the router has no default handler.
+	ErrUnknownEvent = errors.New("unknown event")
+	// ErrHandlerFailed wraps the errors returned by event handlers.
+	ErrHandlerFailed = errors.New("event handler failed")
+)
+
+// HTTPError is an error of an event handler responded with a specific status,
+// e.g. 422 for an event the handler will never accept.
+// RetryAfter is sent as the Retry-After header when positive, see RetryLater.
+type HTTPError struct {
+	Code       int
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// EventHandler handles the events of one name, with the payload decoded into T.
+type EventHandler[T any] func(ctx context.Context, webhook *Webhook, payload T) error
+
+// DefaultHandler handles the events without a registered handler.
+type DefaultHandler func(ctx context.Context, webhook *Webhook, event string) error
+
+// Router dispatches verified callbacks to the handler of their event name.
+// It must run behind Verifier.Middleware and responds 200 when the handler succeeds.
+// Requests without a verified webhook in their context are rejected with ErrNotVerified.
+type Router struct {
+	eventKey     string
+	handlers     map[string]func(ctx context.Context, webhook *Webhook) error
+	fallback     DefaultHandler
+	errorHandler ErrorHandler
+}
+
+type RouterOption func(*Router)
+
+// WithEventKey sets the payload field holding the event name, DefaultEventKey by default.
+func WithEventKey(key string) RouterOption {
+	return func(r *Router) {
+		r.eventKey = key
+	}
+}
+
+// WithDefaultHandler sets the handler of the events without a registered handler.
+func WithDefaultHandler(h DefaultHandler) RouterOption {
+	return func(r *Router) {
+		r.fallback = h
+	}
+}
+
+// WithRouterErrorHandler sets how the router responds to callbacks it could not handle.
+func WithRouterErrorHandler(h ErrorHandler) RouterOption {
+	return func(r *Router) {
+		r.errorHandler = h
+	}
+}
+
+func NewRouter(opts ...RouterOption) *Router {
+	r := &Router{
+		eventKey:     DefaultEventKey,
+		handlers:     make(map[string]func(ctx context.Context, webhook *Webhook) error),
+		errorHandler: DefaultErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Handle registers h for the events named event, decoding their payload into T.
+// It panics when a handler is already registered for event.
+func Handle[T any](r *Router, event string, h EventHandler[T]) {
+	if _, ok := r.handlers[event]; ok {
+		panic(fmt.Sprintf("callbackreceiver: multiple handlers registered for event %q", event))
+	}
+
+	r.handlers[event] = func(ctx context.Context, webhook *Webhook) error {
+		var payload T
+		if err := json.Unmarshal(webhook.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+		}
+		if err := h(ctx, webhook, payload); err != nil {
+			return fmt.Errorf("%w: %w", ErrHandlerFailed, err)
+		}
+		return nil
+	}
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	webhook, ok := FromContext(req.Context())
+	if !ok {
+		// never dispatch a payload nobody verified
+		r.errorHandler(w, req, ErrNotVerified)
+		return
+	}
+
+	if err := r.Dispatch(req.Context(), webhook); err != nil {
+		r.errorHandler(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Dispatch calls the handler of the event of a verified webhook, e.g. as the AsyncHandler of an AsyncReceiver.
+func (r *Router) Dispatch(ctx context.Context, webhook *Webhook) error {
+	event, err := r.eventName(webhook.Payload)
+	if err != nil {
+		return err
+	}
+
+	if h, ok := r.handlers[event]; ok {
+		return h(ctx, webhook)
+	}
+
+	if r.fallback == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownEvent, event)
+	}
+	if err := r.fallback(ctx, webhook, event); err != nil {
+		return fmt.Errorf("%w: %w", ErrHandlerFailed, err)
+	}
+	return nil
+}
+
+// eventName returns the event name of the payload.
+func (r *Router) eventName(payload []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	var event string
+	if raw, ok := fields[r.eventKey]; !ok || json.Unmarshal(raw, &event) != nil || event == "" {
+		return "", fmt.Errorf("%w: missing %s", ErrInvalidPayload, r.eventKey)
+	}
+	return event, nil
+}
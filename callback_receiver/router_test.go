@@ -0,0 +1,156 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type paymentSuccess struct {
+	Event  string  `json:"event"`
+	Amount float64 `json:"amount"`
+}
+
+func TestRouter(t *testing.T) {
+	var got paymentSuccess
+	router := NewRouter(WithDefaultHandler(func(ctx context.Context, webhook *Webhook, event string) error {
+		if event == "refund" {
+			return nil
+		}
+		return errors.New("unexpected event")
+	}))
+	Handle(router, "payment_success", func(ctx context.Context, webhook *Webhook, payload paymentSuccess) error {
+		got = payload
+		return nil
+	})
+	Handle(router, "payment_failed", func(ctx context.Context, webhook *Webhook, payload map[string]interface{}) error {
+		return errors.New("database unavailable")
+	})
+	Handle(router, "payment_expired", func(ctx context.Context, webhook *Webhook, payload map[string]interface{}) error {
+		return &HTTPError{Code: http.StatusGone, Err: errors.New("payment no longer tracked")}
+	})
+
+	tests := []struct {
+		name       string
+		payload    string
+		wantStatus int
+	}{
+		{
+			name:       "dispatch to typed handler",
+			payload:    `{"event":"payment_success","amount":100.5}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "dispatch to default handler",
+			payload:    `{"event":"refund"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "fail on default handler error",
+			payload:    `{"event":"chargeback"}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "fail on handler error",
+			payload:    `{"event":"payment_failed"}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "respond handler http error",
+			payload:    `{"event":"payment_expired"}`,
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "reject payload of wrong type",
+			payload:    `{"event":"payment_success","amount":"100.5"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "reject payload without event",
+			payload:    `{"amount":100.5}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "reject invalid json",
+			payload:    `{"event":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewVerifier(testSecret, WithClock(testClock)).Middleware(router)
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/callback", strings.NewReader(tt.payload))
+			r.Header.Set(SignatureHeader, sign(testSecret, "1700000000", tt.payload))
+			r.Header.Set(TimestampHeader, "1700000000")
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+
+	if got.Amount != 100.5 {
+		t.Errorf("expected decoded amount 100.5, but got %v", got.Amount)
+	}
+}
+
+func TestRouterUnknownEvent(t *testing.T) {
+	router := NewRouter(WithEventKey("type"))
+	Handle(router, "payment_success", func(ctx context.Context, webhook *Webhook, payload paymentSuccess) error {
+		return nil
+	})
+
+	for payload, want := range map[string]int{
+		`{"type":"payment_success"}`:  http.StatusOK,
+		`{"type":"refund"}`:           http.StatusUnprocessableEntity,
+		`{"event":"payment_success"}`: http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/v1/callback", nil)
+		r = r.WithContext(NewContext(r.Context(), &Webhook{Payload: []byte(payload)}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("expected status %d for %s, but got %d", want, payload, w.Code)
+		}
+	}
+}
+
+func TestRouterNotVerified(t *testing.T) {
+	router := NewRouter(WithDefaultHandler(func(ctx context.Context, webhook *Webhook, event string) error {
+		t.Errorf("expected unverified callback not to be dispatched")
+		return nil
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/callback", strings.NewReader(`{"event":"payment_success"}`)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, but got %d", http.StatusInternalServerError, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Verifier.Middleware") {
+		t.Errorf("expected the response to name the missing verification, but got %q", w.Body.String())
+	}
+}
+
+func TestHandleTwice(t *testing.T) {
+	router := NewRouter()
+	Handle(router, "payment_success", func(ctx context.Context, webhook *Webhook, payload paymentSuccess) error {
+		return nil
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering a second handler to panic")
+		}
+	}()
+	Handle(router, "payment_success", func(ctx context.Context, webhook *Webhook, payload paymentSuccess) error {
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrMissingTenant is returned when the tenant of a request can not be read.
+	ErrMissingTenant = errors.New("missing tenant")
+	// ErrUnknownTenant is returned by a SecretStore that has no secrets for a tenant.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrSecretStore wraps the errors of a SecretStore other than ErrUnknownTenant.
+	ErrSecretStore = errors.New("secret store failed")
+)
+
+// Secret is a webhook secret accepted by a Verifier.
+type Secret struct {
+	// ID identifies the secret in the verified webhook, e.g. the date it was issued
+	ID string
+	// Value is the secret the callbacks are signed with
+	Value string
+	// NotAfter is the time the secret stops being accepted, the zero time never expires
+	NotAfter time.Time
+}
+
+// activeAt reports whether the secret is accepted at t.
+func (s Secret) activeAt(t time.Time) bool {
+	return s.Value != "" && (s.NotAfter.IsZero() || !t.After(s.NotAfter))
+}
+
+// SecretStore looks up the secrets of a tenant, so one endpoint can serve many merchants.
+type SecretStore interface {
+	// Secrets returns the secrets of tenant in the order they are tried,
+	// or ErrUnknownTenant when the tenant has none.
+	Secrets(ctx context.Context, tenant string) ([]Secret, error)
+}
+
+// StaticSecretStore is a SecretStore holding the secrets of each tenant in memory.
+type StaticSecretStore map[string][]Secret
+
+func (s StaticSecretStore) Secrets(_ context.Context, tenant string) ([]Secret, error) {
+	secrets, ok := s[tenant]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+	return secrets, nil
+}
+
+// TenantFunc returns the tenant a request is sent to.
+type TenantFunc func(r *http.Request) (string, error)
+
+// TenantFromHeader reads the tenant from the header name.
+func TenantFromHeader(name string) TenantFunc {
+	return func(r *http.Request) (string, error) {
+		return requireTenant(r.Header.Get(name))
+	}
+}
+
+// TenantFromQuery reads the tenant from the query parameter name.
+func TenantFromQuery(name string) TenantFunc {
+	return func(r *http.Request) (string, error) {
+		return requireTenant(r.URL.Query().Get(name))
+	}
+}
+
+// TenantFromPath reads the tenant from the path wildcard name of an http.ServeMux pattern,
+// e.g. "tenant" in "POST /callbacks/{tenant}".
+func TenantFromPath(name string) TenantFunc {
+	return func(r *http.Request) (string, error) {
+		return requireTenant(r.PathValue(name))
+	}
+}
+
+func requireTenant(tenant string) (string, error) {
+	if tenant == "" {
+		return "", ErrMissingTenant
+	}
+	return tenant, nil
+}
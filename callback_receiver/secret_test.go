@@ -0,0 +1,219 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifierSecretRotation(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+
+	tests := []struct {
+		name         string
+		secret       string
+		secrets      []Secret
+		signedWith   string
+		wantSecretID string
+		wantErr      error
+	}{
+		{
+			name:       "match primary secret",
+			secret:     testSecret,
+			secrets:    []Secret{{ID: "old", Value: "old secret"}},
+			signedWith: testSecret,
+		},
+		{
+			name:         "match rotated secret",
+			secret:       testSecret,
+			secrets:      []Secret{{ID: "old", Value: "old secret"}},
+			signedWith:   "old secret",
+			wantSecretID: "old",
+		},
+		{
+			name: "match secrets in order",
+			secrets: []Secret{
+				{ID: "new", Value: "new secret"},
+				{ID: "old", Value: "old secret"},
+			},
+			signedWith:   "new secret",
+			wantSecretID: "new",
+		},
+		{
+			name:         "accept secret until not after",
+			secret:       testSecret,
+			secrets:      []Secret{{ID: "old", Value: "old secret", NotAfter: testClock()}},
+			signedWith:   "old secret",
+			wantSecretID: "old",
+		},
+		{
+			name:       "reject expired secret",
+			secret:     testSecret,
+			secrets:    []Secret{{ID: "old", Value: "old secret", NotAfter: testClock().Add(-time.Second)}},
+			signedWith: "old secret",
+			wantErr:    ErrSignatureMismatch,
+		},
+		{
+			name:       "reject unknown secret",
+			secret:     testSecret,
+			secrets:    []Secret{{ID: "old", Value: "old secret"}},
+			signedWith: "other secret",
+			wantErr:    ErrSignatureMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(tt.secret, WithSecrets(tt.secrets...), WithClock(testClock))
+			webhook, err := verifier.VerifyEvent(sign(tt.signedWith, timestamp, payload), timestamp, "", strings.NewReader(payload))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && webhook.SecretID != tt.wantSecretID {
+				t.Errorf("expected secret id %q, but got %q", tt.wantSecretID, webhook.SecretID)
+			}
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Secrets(context.Context, string) ([]Secret, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestVerifierSecretStore(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	store := StaticSecretStore{
+		"merchant-a": {{ID: "a-1", Value: "secret a"}},
+		"merchant-b": {{ID: "b-2", Value: "secret b2"}, {ID: "b-1", Value: "secret b1"}},
+	}
+
+	tests := []struct {
+		name       string
+		store      SecretStore
+		tenant     string
+		signedWith string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "verify with tenant secret",
+			store:      store,
+			tenant:     "merchant-a",
+			signedWith: "secret a",
+			wantStatus: http.StatusOK,
+			wantBody:   "merchant-a a-1",
+		},
+		{
+			name:       "verify with rotated tenant secret",
+			store:      store,
+			tenant:     "merchant-b",
+			signedWith: "secret b1",
+			wantStatus: http.StatusOK,
+			wantBody:   "merchant-b b-1",
+		},
+		{
+			name:       "reject secret of other tenant",
+			store:      store,
+			tenant:     "merchant-b",
+			signedWith: "secret a",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "reject unknown tenant",
+			store:      store,
+			tenant:     "merchant-c",
+			signedWith: "secret a",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "reject missing tenant",
+			store:      store,
+			signedWith: "secret a",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "fail on store error",
+			store:      failingStore{},
+			tenant:     "merchant-a",
+			signedWith: "secret a",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier("", WithSecretStore(tt.store, TenantFromHeader("X-Tenant")), WithClock(testClock))
+			handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				webhook, _ := FromContext(r.Context())
+				_, _ = w.Write([]byte(webhook.Tenant + " " + webhook.SecretID))
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(payload))
+			req.Header.Set(SignatureHeader, sign(tt.signedWith, timestamp, payload))
+			req.Header.Set(TimestampHeader, timestamp)
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant", tt.tenant)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, but got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSecretStoreWithoutTenantFunc(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	verifier := NewVerifier("",
+		WithSecretStore(StaticSecretStore{"merchant-a": {{ID: "a-1", Value: "secret a"}}}, nil),
+		WithClock(testClock),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(payload))
+	req.Header.Set(SignatureHeader, sign("secret a", timestamp, payload))
+	req.Header.Set(TimestampHeader, timestamp)
+
+	if _, err := verifier.VerifyRequest(req); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("expected to get %v, but got %v", ErrMissingTenant, err)
+	}
+}
+
+func TestTenantFromPath(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	verifier := NewVerifier("",
+		WithSecretStore(StaticSecretStore{"merchant-a": {{ID: "a-1", Value: "secret a"}}}, TenantFromPath("tenant")),
+		WithClock(testClock),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /callbacks/{tenant}", verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/callbacks/merchant-a", strings.NewReader(payload))
+	req.Header.Set(SignatureHeader, sign("secret a", timestamp, payload))
+	req.Header.Set(TimestampHeader, timestamp)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, but got %d", http.StatusNoContent, rec.Code)
+	}
+}
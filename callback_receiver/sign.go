@@ -0,0 +1,100 @@
+package callbackreceiver
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignOptions sets how Sign signs a callback.
+type SignOptions struct {
+	// Time is the time the callback is signed at.
+	Time time.Time
+	// EventID is the id of the event of the callback, empty for a legacy callback signed without it.
+	EventID string
+	// Versions are the versions of the versioned signature header. Without versions
+	// the legacy hex HMAC-SHA256 header is sent.
+	Versions []SignatureVersion
+	// Ed25519Key also signs the callback with the Ed25519 key of KeyID when set.
+	KeyID      string
+	Ed25519Key ed25519.PrivateKey
+	// Standard also signs the callback as in the Standard Webhooks specification,
+	// with EventID as the webhook-id.
+	Standard bool
+}
+
+// Sign returns the signature headers of a callback with payload signed with secret,
+// as the callback service sends them: X-MP-Time, X-MP-SIGNATURE, X-MP-Event-ID unless the
+// callback is a legacy one, and the Ed25519 and Standard Webhooks headers when opts asks for them.
+// The attempt and delivery id headers are not signed and are left to the caller.
+func Sign(secret string, payload []byte, opts SignOptions) (http.Header, error) {
+	t := strconv.FormatInt(opts.Time.Unix(), 10)
+	material := signedMaterial(t, opts.EventID, payload)
+
+	signature, err := signatureHeaderValue(secret, t, material, opts.Versions)
+	if err != nil {
+		return nil, err
+	}
+
+	h := http.Header{}
+	h.Set(TimestampHeader, t)
+	h.Set(SignatureHeader, signature)
+	if opts.EventID != "" {
+		h.Set(EventIDHeader, opts.EventID)
+	}
+
+	if opts.Ed25519Key != nil {
+		h.Set(Ed25519SignatureHeader, base64.RawURLEncoding.EncodeToString(ed25519.Sign(opts.Ed25519Key, material)))
+		h.Set(KeyIDHeader, opts.KeyID)
+	}
+
+	if opts.Standard {
+		if opts.EventID == "" {
+			return nil, errors.New("standard webhooks signature requires an event id")
+		}
+		key, ok := standardKey(secret)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s secret", StandardSecretPrefix)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(opts.EventID + "." + t + "."))
+		mac.Write(payload)
+
+		h.Set(StandardIDHeader, opts.EventID)
+		h.Set(StandardTimestampHeader, t)
+		h.Set(StandardSignatureHeader, "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+	return h, nil
+}
+
+// signatureHeaderValue returns the X-MP-SIGNATURE header of material signed at t,
+// the legacy hex HMAC-SHA256 without versions or "t=<t>,v1=<hex>,v2=<hex>" otherwise.
+func signatureHeaderValue(secret, t string, material []byte, versions []SignatureVersion) (string, error) {
+	if len(versions) == 0 {
+		return hexHMAC(SignatureV1, secret, material), nil
+	}
+
+	parts := []string{"t=" + t}
+	for _, version := range versions {
+		if version.newHash() == nil {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedSignature, version)
+		}
+		parts = append(parts, string(version)+"="+hexHMAC(version, secret, material))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// hexHMAC returns the hex HMAC of material of version with secret.
+func hexHMAC(version SignatureVersion, secret string, material []byte) string {
+	mac := hmac.New(version.newHash(), []byte(secret))
+	mac.Write(material)
+	return hex.EncodeToString(mac.Sum(nil))
+}
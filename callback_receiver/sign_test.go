@@ -0,0 +1,118 @@
+package callbackreceiver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"event":"payment_success"}`)
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+	pub, priv := newTestKey(t)
+	const standardSecret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+
+	tests := []struct {
+		name     string
+		secret   string
+		opts     SignOptions
+		verifier *Verifier
+		want     string
+	}{
+		{
+			name:     "sign legacy callback without event id",
+			secret:   testSecret,
+			opts:     SignOptions{Time: testClock()},
+			verifier: NewVerifier(testSecret, WithClock(testClock)),
+			want:     sign(testSecret, "1700000000", string(payload)),
+		},
+		{
+			name:     "sign callback with event id",
+			secret:   testSecret,
+			opts:     SignOptions{Time: testClock(), EventID: eventID},
+			verifier: NewVerifier(testSecret, WithClock(testClock)),
+			want:     signEvent(testSecret, "1700000000", eventID, string(payload)),
+		},
+		{
+			name:     "sign versioned header",
+			secret:   testSecret,
+			opts:     SignOptions{Time: testClock(), EventID: eventID, Versions: []SignatureVersion{SignatureV1, SignatureV2}},
+			verifier: NewVerifier(testSecret, WithClock(testClock), WithLegacySignatures(false)),
+			want: "t=1700000000,v1=" + signEvent(testSecret, "1700000000", eventID, string(payload)) +
+				",v2=" + signV2(testSecret, "1700000000", eventID+"."+string(payload)),
+		},
+		{
+			name:     "sign with Ed25519 key",
+			secret:   testSecret,
+			opts:     SignOptions{Time: testClock(), EventID: eventID, KeyID: "key-1", Ed25519Key: priv},
+			verifier: NewVerifier("", WithClock(testClock), WithPublicKeys(StaticKeySet{"key-1": pub})),
+		},
+		{
+			name:     "sign as Standard Webhooks",
+			secret:   standardSecret,
+			opts:     SignOptions{Time: testClock(), EventID: eventID, Standard: true},
+			verifier: NewVerifier(standardSecret, WithClock(testClock), WithStandardWebhooks()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Sign(tt.secret, payload, tt.opts)
+			if err != nil {
+				t.Fatalf("expected to get nil error, but got %v", err)
+			}
+			if tt.want != "" && h.Get(SignatureHeader) != tt.want {
+				t.Errorf("expected signature %s, but got %s", tt.want, h.Get(SignatureHeader))
+			}
+			if h.Get(EventIDHeader) != tt.opts.EventID {
+				t.Errorf("expected event id %q, but got %q", tt.opts.EventID, h.Get(EventIDHeader))
+			}
+
+			webhook, err := tt.verifier.VerifyHeader(h, strings.NewReader(string(payload)))
+			if err != nil || webhook.EventID != tt.opts.EventID {
+				t.Errorf("expected callback of event %q to be verified, but got %+v, %v", tt.opts.EventID, webhook, err)
+			}
+		})
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	payload := []byte(`{"event":"payment_success"}`)
+
+	tests := []struct {
+		name    string
+		secret  string
+		opts    SignOptions
+		wantErr error
+	}{
+		{
+			name:    "reject unsupported version",
+			secret:  testSecret,
+			opts:    SignOptions{Time: time.Now(), Versions: []SignatureVersion{"v9"}},
+			wantErr: ErrUnsupportedSignature,
+		},
+		{
+			name:   "reject Standard Webhooks without event id",
+			secret: testSecret,
+			opts:   SignOptions{Time: time.Now(), Standard: true},
+		},
+		{
+			name:   "reject invalid whsec secret",
+			secret: "whsec_not base64",
+			opts:   SignOptions{Time: time.Now(), EventID: "msg_1", Standard: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Sign(tt.secret, payload, tt.opts)
+			if err == nil || h != nil {
+				t.Fatalf("expected error and no headers, but got %v, %v", h, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package callbackreceiver
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"strings"
+)
+
+// SignatureVersion identifies the algorithm of a signature in the versioned signature header.
+type SignatureVersion string
+
+const (
+	// SignatureV1 is a hex HMAC-SHA256 of the signed material.
+	SignatureV1 SignatureVersion = "v1"
+	// SignatureV2 is a hex HMAC-SHA512 of the signed material.
+	SignatureV2 SignatureVersion = "v2"
+)
+
+// DefaultSignatureVersions are the versions a Verifier accepts by default, strongest first.
+var DefaultSignatureVersions = []SignatureVersion{SignatureV2, SignatureV1}
+
+var (
+	// ErrMalformedSignature is returned when the versioned signature header can not be parsed.
+	ErrMalformedSignature = errors.New("malformed signature header")
+	// ErrUnsupportedSignature is returned when the signature header carries no signature
+	// of a version the verifier accepts, e.g. a legacy signature once legacy signatures are disabled.
+	ErrUnsupportedSignature = errors.New("unsupported signature version")
+)
+
+// newHash returns the hash function of the HMAC of version, nil for unknown versions.
+func (s SignatureVersion) newHash() func() hash.Hash {
+	switch s {
+	case SignatureV1:
+		return sha256.New
+	case SignatureV2:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// signatureHeader is a parsed X-MP-SIGNATURE header.
+//
+// The versioned format is "t=<unix seconds>,v1=<hex>,v2=<hex>", with any number of
+// signatures of each version, e.g. one per secret during a rotation. Versions the receiver
+// does not know are ignored so senders can add new ones. The legacy format is a bare
+// hex HMAC-SHA256, which is a v1 signature without timestamp.
+type signatureHeader struct {
+	timestamp  string
+	signatures map[SignatureVersion][]string
+	legacy     bool
+}
+
+func parseSignatureHeader(value string) (signatureHeader, error) {
+	if !strings.Contains(value, "=") {
+		return signatureHeader{
+			signatures: map[SignatureVersion][]string{SignatureV1: {value}},
+			legacy:     true,
+		}, nil
+	}
+
+	header := signatureHeader{signatures: make(map[SignatureVersion][]string)}
+	for _, part := range strings.Split(value, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || key == "" || value == "" {
+			return signatureHeader{}, ErrMalformedSignature
+		}
+
+		if key == "t" {
+			if header.timestamp != "" {
+				return signatureHeader{}, ErrMalformedSignature
+			}
+			header.timestamp = value
+			continue
+		}
+		header.signatures[SignatureVersion(key)] = append(header.signatures[SignatureVersion(key)], value)
+	}
+	return header, nil
+}
+
+// negotiate returns the first of the accepted versions the header carries a signature of.
+func (h signatureHeader) negotiate(accepted []SignatureVersion) (SignatureVersion, bool) {
+	for _, version := range accepted {
+		if len(h.signatures[version]) > 0 {
+			return version, true
+		}
+	}
+	return "", false
+}
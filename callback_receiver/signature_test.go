@@ -0,0 +1,200 @@
+package callbackreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func signV2(secret, timestamp, payload string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseSignatureHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    signatureHeader
+		wantErr error
+	}{
+		{
+			name:  "parse legacy header",
+			value: "abcdef",
+			want:  signatureHeader{signatures: map[SignatureVersion][]string{SignatureV1: {"abcdef"}}, legacy: true},
+		},
+		{
+			name:  "parse versioned header",
+			value: "t=1700000000,v1=ab,v2=cd,v1=ef",
+			want: signatureHeader{
+				timestamp:  "1700000000",
+				signatures: map[SignatureVersion][]string{SignatureV1: {"ab", "ef"}, SignatureV2: {"cd"}},
+			},
+		},
+		{
+			name:  "keep unknown versions",
+			value: "t=1700000000, v9=ab",
+			want: signatureHeader{
+				timestamp:  "1700000000",
+				signatures: map[SignatureVersion][]string{"v9": {"ab"}},
+			},
+		},
+		{
+			name:    "reject empty signature",
+			value:   "t=1700000000,v1=",
+			wantErr: ErrMalformedSignature,
+		},
+		{
+			name:    "reject part without value",
+			value:   "t=1700000000,v1",
+			wantErr: ErrMalformedSignature,
+		},
+		{
+			name:    "reject repeated timestamp",
+			value:   "t=1700000000,t=1700000001,v1=ab",
+			wantErr: ErrMalformedSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSignatureHeader(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, but got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVerifyVersionedSignature(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	v1 := sign(testSecret, timestamp, payload)
+	v2 := signV2(testSecret, timestamp, payload)
+
+	tests := []struct {
+		name        string
+		signature   string
+		timestamp   string
+		opts        []VerifierOption
+		wantErr     error
+		wantVersion SignatureVersion
+	}{
+		{
+			name:        "accept legacy signature",
+			signature:   v1,
+			timestamp:   timestamp,
+			wantVersion: SignatureV1,
+		},
+		{
+			name:        "accept v1 signature",
+			signature:   "t=" + timestamp + ",v1=" + v1,
+			timestamp:   timestamp,
+			wantVersion: SignatureV1,
+		},
+		{
+			name:        "prefer v2 signature",
+			signature:   "t=" + timestamp + ",v1=" + v1 + ",v2=" + v2,
+			timestamp:   timestamp,
+			wantVersion: SignatureV2,
+		},
+		{
+			name:        "read timestamp from header",
+			signature:   "t=" + timestamp + ",v2=" + v2,
+			wantVersion: SignatureV2,
+		},
+		{
+			name:        "accept any signature of a version",
+			signature:   "t=" + timestamp + ",v2=" + signV2("old secret", timestamp, payload) + ",v2=" + v2,
+			timestamp:   timestamp,
+			wantVersion: SignatureV2,
+		},
+		{
+			name:        "ignore unknown version",
+			signature:   "t=" + timestamp + ",v9=0000,v1=" + v1,
+			timestamp:   timestamp,
+			wantVersion: SignatureV1,
+		},
+		{
+			name:      "check only the negotiated version",
+			signature: "t=" + timestamp + ",v1=" + v1 + ",v2=" + signV2("other secret", timestamp, payload),
+			timestamp: timestamp,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:        "negotiate accepted versions",
+			signature:   "t=" + timestamp + ",v1=" + v1 + ",v2=" + signV2("other secret", timestamp, payload),
+			timestamp:   timestamp,
+			opts:        []VerifierOption{WithSignatureVersions(SignatureV1)},
+			wantVersion: SignatureV1,
+		},
+		{
+			name:      "reject unaccepted version",
+			signature: "t=" + timestamp + ",v1=" + v1,
+			timestamp: timestamp,
+			opts:      []VerifierOption{WithSignatureVersions(SignatureV2)},
+			wantErr:   ErrUnsupportedSignature,
+		},
+		{
+			name:      "ignore accepted version without hash function",
+			signature: "t=" + timestamp + ",v3=" + v1,
+			timestamp: timestamp,
+			opts:      []VerifierOption{WithSignatureVersions("v3")},
+			wantErr:   ErrUnsupportedSignature,
+		},
+		{
+			name:      "reject legacy signature when disabled",
+			signature: v1,
+			timestamp: timestamp,
+			opts:      []VerifierOption{WithLegacySignatures(false)},
+			wantErr:   ErrUnsupportedSignature,
+		},
+		{
+			name:      "reject v1 signature as v2",
+			signature: "t=" + timestamp + ",v2=" + v1,
+			timestamp: timestamp,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject timestamp different from header",
+			signature: "t=" + timestamp + ",v2=" + v2,
+			timestamp: "1700000001",
+			wantErr:   ErrInvalidTimestamp,
+		},
+		{
+			name:      "reject versioned header without timestamp",
+			signature: "v2=" + v2,
+			wantErr:   ErrMissingHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(SignatureHeader, tt.signature)
+			if tt.timestamp != "" {
+				h.Set(TimestampHeader, tt.timestamp)
+			}
+
+			opts := append([]VerifierOption{WithClock(testClock)}, tt.opts...)
+			webhook, err := NewVerifier(testSecret, opts...).VerifyHeader(h, strings.NewReader(payload))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && webhook.SignatureVersion != tt.wantVersion {
+				t.Errorf("expected version %s, but got %s", tt.wantVersion, webhook.SignatureVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package callbackreceiver
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Headers of the Standard Webhooks specification, https://www.standardwebhooks.com.
+const (
+	StandardIDHeader        = "webhook-id"
+	StandardTimestampHeader = "webhook-timestamp"
+	StandardSignatureHeader = "webhook-signature"
+)
+
+// StandardSecretPrefix prefixes the base64 key of a Standard Webhooks secret.
+const StandardSecretPrefix = "whsec_"
+
+// standardKey returns the HMAC key of a Standard Webhooks secret. As in the reference
+// implementations, the "whsec_" prefix is optional and the rest is the base64 key.
+// Secrets without the prefix that are not base64 are used as is, like the X-MP secrets.
+func standardKey(secret string) ([]byte, bool) {
+	encoded, prefixed := strings.CutPrefix(secret, StandardSecretPrefix)
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	switch {
+	case err == nil:
+		return key, true
+	case prefixed:
+		return nil, false
+	default:
+		return []byte(secret), true
+	}
+}
+
+// standardSignatures returns the decoded signatures of the space separated "v1,<base64>" signatures
+// of header. Signatures of other versions are ignored.
+func standardSignatures(header string) [][]byte {
+	var signatures [][]byte
+	for _, part := range strings.Fields(header) {
+		encoded, ok := strings.CutPrefix(part, "v1,")
+		if !ok {
+			continue
+		}
+		if signature, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			signatures = append(signatures, signature)
+		}
+	}
+	return signatures
+}
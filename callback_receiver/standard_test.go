@@ -0,0 +1,146 @@
+package callbackreceiver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test vector published with the Standard Webhooks specification.
+const (
+	standardSecret    = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	standardID        = "msg_p5jXN8AQM9LWM0D4loKWxJek"
+	standardTimestamp = "1614265330"
+	standardPayload   = `{"test": 2432232314}`
+	standardSignature = "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="
+)
+
+func standardClock() time.Time {
+	return time.Unix(1614265330, 0)
+}
+
+func TestVerifyStandardWebhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		header  map[string]string
+		payload string
+		now     time.Time
+		opts    []VerifierOption
+		wantErr error
+	}{
+		{
+			name:   "accept signature of the test vector",
+			secret: standardSecret,
+		},
+		{
+			name:   "accept secret without prefix",
+			secret: strings.TrimPrefix(standardSecret, StandardSecretPrefix),
+		},
+		{
+			name:   "accept one of several signatures",
+			secret: standardSecret,
+			header: map[string]string{
+				StandardSignatureHeader: "v1,Ceo5qEr07ixe2NLpvHk3FH9bwy/WavXrAFQ/9tdO6mc= v1a,invalid " + standardSignature,
+			},
+		},
+		{
+			name:    "reject tampered payload",
+			secret:  standardSecret,
+			payload: `{"test": 2432232315}`,
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "reject tampered id",
+			secret:  standardSecret,
+			header:  map[string]string{StandardIDHeader: "msg_p5jXN8AQM9LWM0D4loKWxJel"},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "reject partial signature",
+			secret:  standardSecret,
+			header:  map[string]string{StandardSignatureHeader: standardSignature[:20]},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "reject signature without version",
+			secret:  standardSecret,
+			header:  map[string]string{StandardSignatureHeader: strings.TrimPrefix(standardSignature, "v1,")},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "reject other secret",
+			secret:  "whsec_" + "QmFkU2VjcmV0QmFkU2VjcmV0",
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "reject missing id",
+			secret:  standardSecret,
+			header:  map[string]string{StandardIDHeader: ""},
+			wantErr: ErrMissingHeaders,
+		},
+		{
+			name:    "reject missing timestamp",
+			secret:  standardSecret,
+			header:  map[string]string{StandardTimestampHeader: ""},
+			wantErr: ErrMissingHeaders,
+		},
+		{
+			name:    "reject old timestamp",
+			secret:  standardSecret,
+			now:     standardClock().Add(DefaultTolerance + time.Second),
+			wantErr: ErrTimestampOutOfWindow,
+		},
+		{
+			name:    "reject future timestamp",
+			secret:  standardSecret,
+			now:     standardClock().Add(-DefaultTolerance - time.Second),
+			wantErr: ErrTimestampOutOfWindow,
+		},
+		{
+			name:    "ignore standard headers when disabled",
+			secret:  standardSecret,
+			opts:    []VerifierOption{},
+			wantErr: ErrMissingHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(StandardIDHeader, standardID)
+			h.Set(StandardTimestampHeader, standardTimestamp)
+			h.Set(StandardSignatureHeader, standardSignature)
+			for name, value := range tt.header {
+				h.Set(name, value)
+			}
+
+			payload := standardPayload
+			if tt.payload != "" {
+				payload = tt.payload
+			}
+
+			now := standardClock()
+			if !tt.now.IsZero() {
+				now = tt.now
+			}
+
+			opts := tt.opts
+			if opts == nil {
+				opts = []VerifierOption{WithStandardWebhooks()}
+			}
+			opts = append(opts, WithClock(func() time.Time { return now }))
+
+			webhook, err := NewVerifier(tt.secret, opts...).VerifyHeader(h, strings.NewReader(payload))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && (webhook.EventID != standardID || webhook.Timestamp != standardTimestamp) {
+				t.Errorf("expected id %s and timestamp %s, but got %s and %s", standardID, standardTimestamp, webhook.EventID, webhook.Timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package callbackreceiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"time"
+)
+
+// PayloadTooLargeError is returned when a body exceeds the maximum body size of a Verifier.
+// It matches ErrPayloadTooLarge with errors.Is.
+type PayloadTooLargeError struct {
+	// Limit is the maximum body size in bytes
+	Limit int64
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("%s: limit is %d bytes", ErrPayloadTooLarge, e.Limit)
+}
+
+func (e *PayloadTooLargeError) Is(target error) bool {
+	return target == ErrPayloadTooLarge
+}
+
+// secretMAC is the HMAC of the signed material under one secret, computed while the payload is read.
+type secretMAC struct {
+	secret Secret
+	mac    hash.Hash
+}
+
+// check is the verification of a callback prepared from its headers.
+// The payload is written to it as it is read, then finish checks its signature.
+type check struct {
+	v          *Verifier
+	d          delivery
+	now        time.Time
+	webhook    *Webhook
+	useEd25519 bool
+	version    SignatureVersion
+	macs       []secretMAC
+	signatures [][]byte
+}
+
+// prepare checks the headers of d and starts the HMAC of the signed material of each secret
+// still accepted, so only the payload is left to write.
+func (v *Verifier) prepare(secrets []Secret, d delivery) (*check, error) {
+	if !v.signed(d) {
+		return nil, ErrMissingHeaders
+	}
+
+	useEd25519 := v.keys != nil && d.ed25519 != ""
+	useStandard := !useEd25519 && v.standard && d.standardSignature != ""
+	var header signatureHeader
+	switch {
+	case useStandard:
+		if d.standardID == "" || d.standardTimestamp == "" {
+			return nil, ErrMissingHeaders
+		}
+		d.eventID, d.timestamp = d.standardID, d.standardTimestamp
+	case !useEd25519:
+		var err error
+		header, err = parseSignatureHeader(d.signature)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case d.timestamp == "":
+			d.timestamp = header.timestamp
+		case header.timestamp != "" && header.timestamp != d.timestamp:
+			return nil, ErrInvalidTimestamp
+		}
+	}
+	if d.timestamp == "" {
+		return nil, ErrMissingHeaders
+	}
+
+	seconds, err := strconv.ParseInt(d.timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidTimestamp
+	}
+
+	var attempt int
+	if d.attempt != "" {
+		attempt, err = strconv.Atoi(d.attempt)
+		if err != nil || attempt < 1 {
+			return nil, ErrInvalidAttempt
+		}
+	}
+
+	c := &check{
+		v:   v,
+		d:   d,
+		now: v.now(),
+		webhook: &Webhook{
+			Timestamp:  d.timestamp,
+			Time:       time.Unix(seconds, 0),
+			EventID:    d.eventID,
+			Attempt:    attempt,
+			DeliveryID: d.deliveryID,
+		},
+		useEd25519: useEd25519,
+	}
+
+	switch {
+	case useEd25519:
+		return c, nil
+	case useStandard:
+		c.version = SignatureV1
+		c.signatures = standardSignatures(d.standardSignature)
+		prefix := d.standardID + "." + d.standardTimestamp + "."
+		for _, secret := range secrets {
+			key, ok := standardKey(secret.Value)
+			if !ok || !secret.activeAt(c.now) {
+				continue
+			}
+			c.start(secret, hmac.New(sha256.New, key), prefix)
+		}
+	default:
+		version, ok := header.negotiate(v.versions)
+		if !ok || (header.legacy && !v.legacy) {
+			return nil, ErrUnsupportedSignature
+		}
+
+		c.version = version
+		for _, signature := range header.signatures[version] {
+			if decoded, err := hex.DecodeString(signature); err == nil {
+				c.signatures = append(c.signatures, decoded)
+			}
+		}
+		prefix := string(signedMaterial(d.timestamp, d.eventID, nil))
+		for _, secret := range secrets {
+			if !secret.activeAt(c.now) {
+				continue
+			}
+			c.start(secret, hmac.New(version.newHash(), []byte(secret.Value)), prefix)
+		}
+	}
+	return c, nil
+}
+
+// start adds the HMAC of secret, with the signed material up to the payload written to it.
+func (c *check) start(secret Secret, mac hash.Hash, prefix string) {
+	mac.Write([]byte(prefix))
+	c.macs = append(c.macs, secretMAC{secret: secret, mac: mac})
+}
+
+// Write adds p to the payload of each HMAC.
+func (c *check) Write(p []byte) (int, error) {
+	for _, m := range c.macs {
+		m.mac.Write(p)
+	}
+	return len(p), nil
+}
+
+// finish checks the signature of the complete payload, then that it was signed within the tolerance window.
+// A timestamp out of the window therefore always comes from an authentic but stale callback.
+func (c *check) finish(ctx context.Context, payload []byte) (*Webhook, error) {
+	webhook := c.webhook
+	if c.useEd25519 {
+		if err := c.v.checkSignature(ctx, payload, c.d); err != nil {
+			return nil, err
+		}
+		webhook.KeyID = c.d.keyID
+	} else {
+		matched, ok := c.match()
+		if !ok {
+			return nil, ErrSignatureMismatch
+		}
+		webhook.SecretID = matched.ID
+		webhook.SignatureVersion = c.version
+	}
+
+	if tolerance := c.v.tolerance; tolerance > 0 {
+		if webhook.Time.Before(c.now.Add(-tolerance)) || webhook.Time.After(c.now.Add(tolerance)) {
+			return nil, ErrTimestampOutOfWindow
+		}
+	}
+
+	webhook.Payload = payload
+	return webhook, nil
+}
+
+// match returns the first secret whose HMAC one of the signatures matches, in constant time.
+func (c *check) match() (Secret, bool) {
+	for _, m := range c.macs {
+		expected := m.mac.Sum(nil)
+		for _, signature := range c.signatures {
+			if hmac.Equal(expected, signature) {
+				return m.secret, true
+			}
+		}
+	}
+	return Secret{}, false
+}
+
+// readPayload reads body up to maxSize bytes, writing it to w as it is read.
+func readPayload(body io.Reader, maxSize int64, w io.Writer) ([]byte, error) {
+	var payload bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(&payload, w), io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	if n > maxSize {
+		return nil, &PayloadTooLargeError{Limit: maxSize}
+	}
+	return payload.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package callbackreceiver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// countingReader is an endless body counting the bytes read from it.
+type countingReader struct {
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	r.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestVerifyStream(t *testing.T) {
+	payload := `{"event":"payment_success","amount":100.5}`
+	timestamp := "1700000000"
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+
+	header := http.Header{}
+	header.Set(TimestampHeader, timestamp)
+	header.Set(EventIDHeader, eventID)
+	header.Set(SignatureHeader, signEvent(testSecret, timestamp, eventID, payload))
+
+	tests := []struct {
+		name    string
+		body    io.Reader
+		opts    []VerifierOption
+		wantErr error
+	}{
+		{
+			name: "verify body read one byte at a time",
+			body: iotest.OneByteReader(strings.NewReader(payload)),
+		},
+		{
+			name: "verify body of the maximum size",
+			body: strings.NewReader(payload),
+			opts: []VerifierOption{WithMaxBodySize(int64(len(payload)))},
+		},
+		{
+			name:    "reject body over the maximum size",
+			body:    strings.NewReader(payload),
+			opts:    []VerifierOption{WithMaxBodySize(int64(len(payload)) - 1)},
+			wantErr: ErrPayloadTooLarge,
+		},
+		{
+			name:    "reject tampered body",
+			body:    strings.NewReader(payload + " "),
+			wantErr: ErrSignatureMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]VerifierOption{WithClock(testClock)}, tt.opts...)
+			webhook, err := NewVerifier(testSecret, opts...).VerifyHeader(header, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && string(webhook.Payload) != payload {
+				t.Errorf("expected to get %s, but got %s", payload, webhook.Payload)
+			}
+		})
+	}
+}
+
+func TestVerifyStreamLimit(t *testing.T) {
+	header := http.Header{}
+	header.Set(TimestampHeader, "1700000000")
+	header.Set(SignatureHeader, sign(testSecret, "1700000000", ""))
+
+	body := &countingReader{}
+	_, err := NewVerifier(testSecret, WithClock(testClock), WithMaxBodySize(1024)).VerifyHeader(header, body)
+
+	var tooLarge *PayloadTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Errorf("expected to get a payload too large error with limit 1024, but got %v", err)
+	}
+	if body.n > 1025 {
+		t.Errorf("expected to read at most 1025 bytes, but read %d", body.n)
+	}
+}
+
+func TestVerifyStreamReadError(t *testing.T) {
+	header := http.Header{}
+	header.Set(TimestampHeader, "1700000000")
+	header.Set(SignatureHeader, sign(testSecret, "1700000000", ""))
+
+	readErr := errors.New("connection reset")
+	_, err := NewVerifier(testSecret, WithClock(testClock)).VerifyHeader(header, iotest.ErrReader(readErr))
+	if !errors.Is(err, readErr) {
+		t.Errorf("expected to get %v, but got %v", readErr, err)
+	}
+}
+
+func TestMiddlewareRejectsAnnouncedLargeBody(t *testing.T) {
+	called := false
+	handler := NewVerifier(testSecret, WithClock(testClock), WithMaxBodySize(8)).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}),
+	)
+
+	body := &countingReader{}
+	r := httptest.NewRequest(http.MethodPost, "/v1/callback", io.LimitReader(body, 1<<20))
+	r.ContentLength = 1 << 20
+	r.Header.Set(TimestampHeader, "1700000000")
+	r.Header.Set(SignatureHeader, sign(testSecret, "1700000000", ""))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected to get %v, but got %v", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	if called || body.n != 0 {
+		t.Errorf("expected the body to be rejected unread, but read %d bytes and called handler %v", body.n, called)
+	}
+}
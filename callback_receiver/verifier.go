@@ -0,0 +1,357 @@
+package callbackreceiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader is the header holding the hex HMAC-SHA256 signature of a callback.
+	SignatureHeader = "X-MP-SIGNATURE"
+	// TimestampHeader is the header holding the time the callback was signed at.
+	TimestampHeader = "X-MP-Time"
+	// EventIDHeader is the header holding the id of the event, it is part of the signed material.
+	EventIDHeader = "X-MP-Event-ID"
+	// AttemptHeader is the header holding the delivery attempt number of the event, starting at 1.
+	AttemptHeader = "X-MP-Attempt"
+	// DeliveryIDHeader is the header holding the id of the delivery attempt.
+	DeliveryIDHeader = "X-MP-Delivery-ID"
+	// Ed25519SignatureHeader is the header holding the base64url Ed25519 signature of a callback.
+	Ed25519SignatureHeader = "X-MP-Signature-Ed25519"
+	// KeyIDHeader is the header holding the id of the Ed25519 key a callback is signed with.
+	KeyIDHeader = "X-MP-Key-ID"
+	// DefaultMaxBodySize is the largest callback body a Verifier reads by default.
+	DefaultMaxBodySize = 1 << 20
+	// DefaultTolerance is how far the signing time of a callback may be from now by default.
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrMissingHeaders is returned when the signature or timestamp header is missing.
+	ErrMissingHeaders = errors.New("missing signature headers")
+	// ErrInvalidTimestamp is returned when the timestamp header is not in Unix seconds.
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+	// ErrTimestampOutOfWindow is returned when the signing time is outside the tolerance window,
+	// e.g. when a captured callback is replayed.
+	ErrTimestampOutOfWindow = errors.New("timestamp out of tolerance window")
+	// ErrInvalidAttempt is returned when the attempt header is not a positive number.
+	ErrInvalidAttempt = errors.New("invalid attempt")
+	// ErrPayloadTooLarge is matched by the PayloadTooLargeError of a body exceeding the maximum body size.
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrSignatureMismatch is returned when the signature does not match the payload.
+	ErrSignatureMismatch = errors.New("signature mismatch")
+)
+
+// Webhook is a callback whose signature was verified.
+type Webhook struct {
+	// Payload is the verified request body
+	Payload []byte
+	// Timestamp is the value of the X-MP-Time header
+	Timestamp string
+	// Time is the time the callback was signed at
+	Time time.Time
+	// SecretID is the ID of the secret the signature matched
+	SecretID string
+	// KeyID is the id of the Ed25519 key the signature matched, empty for HMAC signatures
+	KeyID string
+	// SignatureVersion is the version of the HMAC signature that matched, empty for Ed25519 signatures
+	SignatureVersion SignatureVersion
+	// Tenant is the tenant the secrets were looked up for, empty without a SecretStore
+	Tenant string
+	// EventID is the signed id of the event, empty for callbacks without the event id header.
+	// Deliveries of the same event share it, so receivers can deduplicate on it.
+	EventID string
+	// Attempt is the delivery attempt number of the event, 0 when the header is missing.
+	// It is not signed and must only be used for logging.
+	Attempt int
+	// DeliveryID is the id of the delivery attempt, it is not signed
+	DeliveryID string
+}
+
+// Verifier verifies signed callbacks sent by the callback service.
+type Verifier struct {
+	secrets      []Secret
+	store        SecretStore
+	tenant       TenantFunc
+	keys         PublicKeyStore
+	versions     []SignatureVersion
+	legacy       bool
+	standard     bool
+	maxBodySize  int64
+	tolerance    time.Duration
+	now          func() time.Time
+	errorHandler ErrorHandler
+}
+
+type VerifierOption func(*Verifier)
+
+// WithMaxBodySize sets the largest body the verifier reads, DefaultMaxBodySize by default.
+// Larger bodies are rejected with a PayloadTooLargeError once the limit is reached.
+func WithMaxBodySize(n int64) VerifierOption {
+	return func(v *Verifier) {
+		v.maxBodySize = n
+	}
+}
+
+// WithTolerance sets how far the signing time of a callback may be from now, in either direction.
+// A zero tolerance disables the check, e.g. to verify captured callbacks.
+func WithTolerance(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.tolerance = d
+	}
+}
+
+// WithClock sets the clock the signing time is compared to.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// WithSecrets adds secrets tried in order after the secret passed to NewVerifier,
+// e.g. the previous secret of a merchant while callbacks signed with it are still queued.
+func WithSecrets(secrets ...Secret) VerifierOption {
+	return func(v *Verifier) {
+		v.secrets = append(v.secrets, secrets...)
+	}
+}
+
+// WithSecretStore makes VerifyRequest and the middleware look up the secrets of the tenant
+// returned by tenant in store instead of using the secrets of the verifier.
+// Without a tenant function, requests are rejected with ErrMissingTenant.
+func WithSecretStore(store SecretStore, tenant TenantFunc) VerifierOption {
+	return func(v *Verifier) {
+		v.store = store
+		v.tenant = tenant
+	}
+}
+
+// WithSignatureVersions sets the versions of HMAC signatures the verifier accepts, in order of preference.
+// A callback signed with several versions is only checked against the first accepted one it carries.
+// Versions the package has no hash function for are ignored.
+func WithSignatureVersions(versions ...SignatureVersion) VerifierOption {
+	return func(v *Verifier) {
+		v.versions = nil
+		for _, version := range versions {
+			if version.newHash() != nil {
+				v.versions = append(v.versions, version)
+			}
+		}
+	}
+}
+
+// WithLegacySignatures sets whether the verifier accepts the legacy unversioned signature header,
+// true by default. Receivers disable it once every sender uses the versioned header.
+func WithLegacySignatures(accept bool) VerifierOption {
+	return func(v *Verifier) {
+		v.legacy = accept
+	}
+}
+
+// WithStandardWebhooks makes the verifier accept callbacks signed as in the Standard Webhooks specification,
+// with the webhook-id, webhook-timestamp and webhook-signature headers. Their "whsec_" secrets are
+// passed as is to NewVerifier or WithSecrets. Callbacks without these headers are verified as usual.
+func WithStandardWebhooks() VerifierOption {
+	return func(v *Verifier) {
+		v.standard = true
+	}
+}
+
+// WithPublicKeys makes the verifier check the Ed25519 signature of callbacks carrying one
+// with the keys of store. Callbacks without it are checked against the HMAC secrets,
+// so a verifier built with an empty secret only accepts Ed25519 signatures.
+func WithPublicKeys(store PublicKeyStore) VerifierOption {
+	return func(v *Verifier) {
+		v.keys = store
+	}
+}
+
+// WithErrorHandler sets how the middleware responds to requests that fail verification.
+func WithErrorHandler(h ErrorHandler) VerifierOption {
+	return func(v *Verifier) {
+		v.errorHandler = h
+	}
+}
+
+// NewVerifier returns a verifier accepting callbacks signed with secret.
+// An empty secret is skipped, so a verifier can be built from WithSecrets or WithSecretStore alone.
+func NewVerifier(secret string, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		secrets:      []Secret{{Value: secret}},
+		maxBodySize:  DefaultMaxBodySize,
+		tolerance:    DefaultTolerance,
+		now:          time.Now,
+		errorHandler: DefaultErrorHandler,
+		versions:     DefaultSignatureVersions,
+		legacy:       true,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify is VerifyEvent for legacy callbacks sent without an X-MP-Event-ID header.
+//
+// Deprecated: callbacks of current senders are signed with their event id and always fail Verify
+// with ErrSignatureMismatch. Use VerifyEvent or VerifyHeader instead.
+func (v *Verifier) Verify(signature, timestamp string, body io.Reader) (*Webhook, error) {
+	return v.VerifyEvent(signature, timestamp, "", body)
+}
+
+// VerifyEvent reads at most the maximum body size from body and checks it against the signature headers.
+// It can be called from any framework with the raw X-MP-SIGNATURE, X-MP-Time and X-MP-Event-ID
+// header values and body. An empty eventID verifies a legacy callback sent without the event id header.
+func (v *Verifier) VerifyEvent(signature, timestamp, eventID string, body io.Reader) (*Webhook, error) {
+	d := delivery{signature: signature, timestamp: timestamp, eventID: eventID}
+	return v.verifyBody(context.Background(), v.secrets, d, body)
+}
+
+// VerifyHeader is Verify with the signature, timestamp and delivery headers read from h.
+func (v *Verifier) VerifyHeader(h http.Header, body io.Reader) (*Webhook, error) {
+	return v.verifyBody(context.Background(), v.secrets, deliveryFromHeader(h), body)
+}
+
+// VerifyTenant is VerifyHeader with the secrets of tenant looked up in the SecretStore of the verifier.
+func (v *Verifier) VerifyTenant(ctx context.Context, tenant string, h http.Header, body io.Reader) (*Webhook, error) {
+	if v.store == nil {
+		return nil, fmt.Errorf("%w: no secret store configured", ErrSecretStore)
+	}
+
+	secrets, err := v.store.Secrets(ctx, tenant)
+	if err != nil {
+		if errors.Is(err, ErrUnknownTenant) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrSecretStore, err)
+	}
+
+	webhook, err := v.verifyBody(ctx, secrets, deliveryFromHeader(h), body)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Tenant = tenant
+	return webhook, nil
+}
+
+// delivery holds the header values of a callback delivery.
+type delivery struct {
+	signature  string
+	timestamp  string
+	eventID    string
+	attempt    string
+	deliveryID string
+	ed25519    string
+	keyID      string
+	// the Standard Webhooks headers
+	standardID        string
+	standardTimestamp string
+	standardSignature string
+}
+
+func deliveryFromHeader(h http.Header) delivery {
+	return delivery{
+		signature:  h.Get(SignatureHeader),
+		timestamp:  h.Get(TimestampHeader),
+		eventID:    h.Get(EventIDHeader),
+		attempt:    h.Get(AttemptHeader),
+		deliveryID: h.Get(DeliveryIDHeader),
+		ed25519:    h.Get(Ed25519SignatureHeader),
+		keyID:      h.Get(KeyIDHeader),
+
+		standardID:        h.Get(StandardIDHeader),
+		standardTimestamp: h.Get(StandardTimestampHeader),
+		standardSignature: h.Get(StandardSignatureHeader),
+	}
+}
+
+// signed reports whether d carries a signature the verifier can check.
+func (v *Verifier) signed(d delivery) bool {
+	return d.signature != "" || (v.keys != nil && d.ed25519 != "") || (v.standard && d.standardSignature != "")
+}
+
+// verifyBody verifies the callback of d while reading at most the maximum body size from body,
+// so the payload is only held once in memory and never beyond the limit.
+func (v *Verifier) verifyBody(ctx context.Context, secrets []Secret, d delivery, body io.Reader) (*Webhook, error) {
+	c, err := v.prepare(secrets, d)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := readPayload(body, v.maxBodySize, c)
+	if err != nil {
+		return nil, err
+	}
+	return c.finish(ctx, payload)
+}
+
+// verify verifies the callback of d with a payload already in memory.
+func (v *Verifier) verify(ctx context.Context, secrets []Secret, payload []byte, d delivery) (*Webhook, error) {
+	c, err := v.prepare(secrets, d)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Write(payload)
+	return c.finish(ctx, payload)
+}
+
+// checkSignature checks the Ed25519 signature of payload with the public key of its key id.
+func (v *Verifier) checkSignature(ctx context.Context, payload []byte, d delivery) error {
+	if d.keyID == "" {
+		return ErrUnknownKey
+	}
+
+	key, err := v.keys.PublicKey(ctx, d.keyID)
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) || errors.Is(err, ErrSecretStore) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrSecretStore, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(d.ed25519, "="))
+	if err != nil || !ed25519.Verify(key, signedMaterial(d.timestamp, d.eventID, payload), signature) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// VerifyRequest verifies r and replaces its body with the verified payload,
+// so handlers can read it again. With a SecretStore, the secrets are those of the tenant of r.
+// Requests announcing a body larger than the maximum body size are rejected without reading it.
+func (v *Verifier) VerifyRequest(r *http.Request) (*Webhook, error) {
+	if r.ContentLength > v.maxBodySize {
+		return nil, &PayloadTooLargeError{Limit: v.maxBodySize}
+	}
+
+	var webhook *Webhook
+	var err error
+	if v.store != nil {
+		var tenant string
+		if v.tenant == nil {
+			err = fmt.Errorf("%w: no tenant function configured", ErrMissingTenant)
+		} else {
+			tenant, err = v.tenant(r)
+		}
+		if err == nil {
+			webhook, err = v.VerifyTenant(r.Context(), tenant, r.Header, r.Body)
+		}
+	} else {
+		webhook, err = v.verifyBody(r.Context(), v.secrets, deliveryFromHeader(r.Header), r.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(webhook.Payload))
+	return webhook, nil
+}
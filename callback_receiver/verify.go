@@ -1,32 +1,42 @@
 package callbackreceiver
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"fmt"
+	"context"
 )
 
+// VerifyRequestHash verifies the hash of a payload signed at t with the secret sk,
+// as callbacks of older senders without an X-MP-Event-ID header are signed.
+// Callbacks carrying an X-MP-Event-ID header are signed with their event id and must be
+// verified with VerifyEventHash instead.
+// Timestamps further than DefaultTolerance from now are rejected.
+// Payloads read from a request are better verified with Verifier.VerifyEvent, which reads them
+// up to a maximum size and computes the hash as it reads.
 func VerifyRequestHash(sk string, payload []byte, t string, hash string) ([]byte, error) {
-	mac := hmac.New(sha256.New, []byte(sk))
-
-	if _, err := mac.Write([]byte(t)); err != nil {
-		return nil, err
-	}
-
-	if _, err := mac.Write([]byte(".")); err != nil {
-		return nil, err
-	}
+	return VerifyEventHash(sk, payload, t, "", hash)
+}
 
-	if _, err := mac.Write([]byte(payload)); err != nil {
+// VerifyEventHash verifies the hash of the payload of the event eventID signed at t with the secret sk,
+// where eventID is the X-MP-Event-ID header of the callback. An empty eventID verifies the
+// legacy signature as VerifyRequestHash does.
+// Timestamps further than DefaultTolerance from now are rejected.
+func VerifyEventHash(sk string, payload []byte, t string, eventID string, hash string) ([]byte, error) {
+	v := NewVerifier(sk)
+	if _, err := v.verify(context.Background(), v.secrets, payload, delivery{signature: hash, timestamp: t, eventID: eventID}); err != nil {
 		return nil, err
 	}
 
-	expectedHash := hex.EncodeToString(mac.Sum(nil))
+	return payload, nil
+}
 
-	if expectedHash != hash {
-		return nil, fmt.Errorf("hash verification failed: expected %s, but got %s", expectedHash, hash)
+// signedMaterial returns the material signed for the payload of the event eventID signed at t,
+// "<t>.<eventID>.<payload>", or "<t>.<payload>" without an event id.
+func signedMaterial(t string, eventID string, payload []byte) []byte {
+	material := make([]byte, 0, len(t)+len(eventID)+len(payload)+2)
+	material = append(material, t...)
+	material = append(material, '.')
+	if eventID != "" {
+		material = append(material, eventID...)
+		material = append(material, '.')
 	}
-
-	return payload, nil
+	return append(material, payload...)
 }
@@ -0,0 +1,310 @@
+package callbackreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRequestHash(t *testing.T) {
+	payload := []byte(`{"event":"payment_success","amount":100.5}`)
+	timestamp := "1700000000"
+	valid := sign(testSecret, timestamp, string(payload))
+
+	tests := []struct {
+		name      string
+		secret    string
+		payload   []byte
+		timestamp string
+		hash      string
+		wantErr   error
+	}{
+		{
+			name:      "accept valid hash",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      valid,
+		},
+		{
+			name:      "accept upper case hash",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      strings.ToUpper(valid),
+		},
+		{
+			name:      "accept mixed case hash",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      strings.ToUpper(valid[:32]) + valid[32:],
+		},
+		{
+			name:      "reject malformed hex",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      "zz" + valid[2:],
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject odd length hex",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      valid[:63],
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject truncated hash",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      valid[:32],
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject extended hash",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      valid + "00",
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject hash of other secret",
+			secret:    "other secret",
+			payload:   payload,
+			timestamp: timestamp,
+			hash:      valid,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject tampered payload",
+			secret:    testSecret,
+			payload:   []byte(`{"event":"payment_success","amount":1000.5}`),
+			timestamp: timestamp,
+			hash:      valid,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject tampered timestamp",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: "1700000001",
+			hash:      valid,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reject empty hash",
+			secret:    testSecret,
+			payload:   payload,
+			timestamp: timestamp,
+			wantErr:   ErrMissingHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(tt.secret, WithClock(func() time.Time { return time.Unix(1700000000, 0) }))
+			_, err := verifier.verify(context.Background(), verifier.secrets, tt.payload, delivery{signature: tt.hash, timestamp: tt.timestamp})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+
+			// errors must not leak the expected signature of the payload
+			expected := sign(tt.secret, tt.timestamp, string(tt.payload))
+			if err != nil && strings.Contains(strings.ToLower(err.Error()), expected) {
+				t.Errorf("expected error to carry no signature, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestHashTolerance(t *testing.T) {
+	payload := []byte(`{"event":"payment_success"}`)
+	now := time.Now().Unix()
+
+	stale := time.Unix(now, 0).Add(-2 * DefaultTolerance).Unix()
+	_, err := VerifyRequestHash(testSecret, payload, strconv.FormatInt(stale, 10), sign(testSecret, strconv.FormatInt(stale, 10), string(payload)))
+	if !errors.Is(err, ErrTimestampOutOfWindow) {
+		t.Errorf("expected to get %v, but got %v", ErrTimestampOutOfWindow, err)
+	}
+
+	got, err := VerifyRequestHash(testSecret, payload, strconv.FormatInt(now, 10), sign(testSecret, strconv.FormatInt(now, 10), string(payload)))
+	if err != nil || string(got) != string(payload) {
+		t.Errorf("expected to get the payload and nil error, but got %s, %v", got, err)
+	}
+}
+
+func TestVerifyEventHash(t *testing.T) {
+	payload := []byte(`{"event":"payment_success"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+	signed := signEvent(testSecret, timestamp, eventID, string(payload))
+	legacy := sign(testSecret, timestamp, string(payload))
+
+	tests := []struct {
+		name    string
+		eventID string
+		hash    string
+		wantErr error
+	}{
+		{name: "accept hash signed with event id", eventID: eventID, hash: signed},
+		{name: "accept legacy hash without event id", hash: legacy},
+		{name: "reject hash of other event id", eventID: "other-event", hash: signed, wantErr: ErrSignatureMismatch},
+		{name: "reject legacy hash with event id", eventID: eventID, hash: legacy, wantErr: ErrSignatureMismatch},
+		{name: "reject hash signed with event id without it", hash: signed, wantErr: ErrSignatureMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyEventHash(testSecret, payload, timestamp, tt.eventID, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && string(got) != string(payload) {
+				t.Errorf("expected to get %s, but got %s", payload, got)
+			}
+		})
+	}
+
+	// VerifyRequestHash only verifies the legacy material
+	if _, err := VerifyRequestHash(testSecret, payload, timestamp, signed); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("expected to get %v, but got %v", ErrSignatureMismatch, err)
+	}
+}
+
+func TestVerifierVerifyEvent(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+	verifier := NewVerifier(testSecret, WithClock(testClock))
+
+	tests := []struct {
+		name      string
+		signature string
+		eventID   string
+		wantErr   error
+	}{
+		{name: "accept callback signed with event id", signature: signEvent(testSecret, timestamp, eventID, payload), eventID: eventID},
+		{name: "accept legacy callback without event id", signature: sign(testSecret, timestamp, payload)},
+		{name: "reject other event id", signature: signEvent(testSecret, timestamp, eventID, payload), eventID: "other-event", wantErr: ErrSignatureMismatch},
+		{name: "reject removed event id", signature: signEvent(testSecret, timestamp, eventID, payload), wantErr: ErrSignatureMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook, err := verifier.VerifyEvent(tt.signature, timestamp, tt.eventID, strings.NewReader(payload))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err == nil && webhook.EventID != tt.eventID {
+				t.Errorf("expected event id %q, but got %q", tt.eventID, webhook.EventID)
+			}
+		})
+	}
+}
+
+func signEvent(secret, timestamp, eventID, payload string) string {
+	return sign(secret, timestamp, eventID+"."+payload)
+}
+
+func TestVerifyHeader(t *testing.T) {
+	payload := `{"event":"payment_success"}`
+	timestamp := "1700000000"
+	eventID := "6f1c3a52-8d0e-4a7b-9d7e-2c1b5b9f0e11"
+
+	tests := []struct {
+		name           string
+		header         map[string]string
+		wantErr        error
+		wantEventID    string
+		wantAttempt    int
+		wantDeliveryID string
+	}{
+		{
+			name: "expose delivery headers",
+			header: map[string]string{
+				SignatureHeader:  signEvent(testSecret, timestamp, eventID, payload),
+				TimestampHeader:  timestamp,
+				EventIDHeader:    eventID,
+				AttemptHeader:    "3",
+				DeliveryIDHeader: "delivery-3",
+			},
+			wantEventID:    eventID,
+			wantAttempt:    3,
+			wantDeliveryID: "delivery-3",
+		},
+		{
+			name: "accept legacy callback without event id",
+			header: map[string]string{
+				SignatureHeader: sign(testSecret, timestamp, payload),
+				TimestampHeader: timestamp,
+			},
+		},
+		{
+			name: "reject tampered event id",
+			header: map[string]string{
+				SignatureHeader: signEvent(testSecret, timestamp, eventID, payload),
+				TimestampHeader: timestamp,
+				EventIDHeader:   "7a2d4b63-9e1f-4b8c-8e8f-3d2c6c0a1f22",
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "reject removed event id",
+			header: map[string]string{
+				SignatureHeader: signEvent(testSecret, timestamp, eventID, payload),
+				TimestampHeader: timestamp,
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "reject invalid attempt",
+			header: map[string]string{
+				SignatureHeader: signEvent(testSecret, timestamp, eventID, payload),
+				TimestampHeader: timestamp,
+				EventIDHeader:   eventID,
+				AttemptHeader:   "first",
+			},
+			wantErr: ErrInvalidAttempt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for name, value := range tt.header {
+				h.Set(name, value)
+			}
+
+			webhook, err := NewVerifier(testSecret, WithClock(testClock)).VerifyHeader(h, strings.NewReader(payload))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected to get %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			if webhook.EventID != tt.wantEventID {
+				t.Errorf("expected event id %q, but got %q", tt.wantEventID, webhook.EventID)
+			}
+			if webhook.Attempt != tt.wantAttempt {
+				t.Errorf("expected attempt %d, but got %d", tt.wantAttempt, webhook.Attempt)
+			}
+			if webhook.DeliveryID != tt.wantDeliveryID {
+				t.Errorf("expected delivery id %q, but got %q", tt.wantDeliveryID, webhook.DeliveryID)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"github.com/avast/retry-go"
+)
+
+func newClient(cfg *config) (callback.Client, error) {
+	if err := cfg.validateClient(); err != nil {
+		return nil, err
+	}
+	return callback.NewAccountClient(cfg.URL, cfg.SecretKey, []retry.Option{
+		retry.Attempts(3),
+		retry.Delay(500 * time.Millisecond),
+		retry.LastErrorOnly(true),
+	}), nil
+}
+
+func runSend(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "send", "")
+	resolve := commonFlags(fs)
+	payloadPath := fs.String("payload", "-", "JSON payload file, - reads stdin")
+	callbackURL := fs.String("callback-url", "", "url the callback is sent to")
+	webhookSecret := webhookSecretFlag(fs, "secret used to sign the callback")
+	method := fs.String("method", string(callback.MethodPost), "HTTP method of the callback")
+	maxRetries := fs.Int64("max-retries", 0, "maximum number of retries")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	payload, err := readPayload(env, *payloadPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	confirmation, err := client.SendCallbackEvent(ctx, callback.CallbackRequestEvent{
+		Payload:       payload,
+		CallbackURL:   *callbackURL,
+		WebhookSecret: callback.Secret(webhookSecret()),
+		Method:        *method,
+		MaxRetries:    *maxRetries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == outputJSON {
+		return writeJSON(env.stdout, confirmation)
+	}
+	_, err = fmt.Fprintln(env.stdout, confirmation.AcknowledgementID)
+	return err
+}
+
+func readPayload(env *env, path string) (map[string]interface{}, error) {
+	var r io.Reader = env.stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	return payload, nil
+}
+
+func runGet(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "get", "<event-id>")
+	resolve := commonFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	eventID, err := oneArg(fs, "event id")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	event, err := client.GetEventDetailByID(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == outputJSON {
+		return writeJSON(env.stdout, event)
+	}
+	return writeEvents(env.stdout, cfg.Output, []callback.Event{*event})
+}
+
+func runList(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "list", "")
+	resolve := commonFlags(fs)
+	filter := fs.String("filter", "", "raw filter query string, e.g. status=FAILED")
+	status := fs.String("status", "", "only list events with this status")
+	page := fs.Int("page", 1, "page to list")
+	perPage := fs.Int("per-page", callback.DefaultPerPage, "events per page")
+	all := fs.Bool("all", false, "list every page starting at -page")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	query, err := url.ParseQuery(*filter)
+	if err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var events []callback.Event
+	err = callback.EachEventPage(ctx, client, query.Encode(), *page, *perPage, func(_ int, list *callback.EventList) error {
+		events = append(events, list.Data...)
+		if !*all {
+			return errStopPaging
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopPaging) {
+		return err
+	}
+
+	return writeEvents(env.stdout, cfg.Output, events)
+}
+
+// errStopPaging stops paging after the first page.
+var errStopPaging = errors.New("stop paging")
+
+func runHistory(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "history", "<event-id>")
+	resolve := commonFlags(fs)
+	filter := fs.String("filter", "", "raw filter query string")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	eventID, err := oneArg(fs, "event id")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var history []callback.CallbackHistory
+	err = callback.EachCallbackHistoryPage(ctx, client, eventID, *filter, 1, callback.DefaultPerPage, func(_ int, list *callback.CallbackHistoryList) error {
+		history = append(history, list.Data...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeHistory(env.stdout, cfg.Output, history)
+}
+
+func runWait(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "wait", "<event-id>")
+	resolve := commonFlags(fs)
+	timeout := fs.Duration("timeout", 5*time.Minute, "how long to wait")
+	interval := fs.Duration("interval", 2*time.Second, "how often to poll the event")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	eventID, err := oneArg(fs, "event id")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var last *callback.Event
+	for {
+		event, err := client.GetEventDetailByID(ctx, eventID)
+		if err != nil {
+			// the timeout may expire during a poll, report the last known status then
+			if ctx.Err() != nil && last != nil {
+				return waitTimeout(last, ctx.Err())
+			}
+			return err
+		}
+		last = event
+
+		if event.Status.IsTerminal() {
+			if err := writeEvents(env.stdout, cfg.Output, []callback.Event{*event}); err != nil {
+				return err
+			}
+			if event.Status != callback.StatusSucceeded {
+				return &exitError{code: 3, msg: fmt.Sprintf("event %s ended with status %s", eventID, event.Status)}
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return waitTimeout(event, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitTimeout returns the error of a wait that timed out while event was not terminal yet.
+func waitTimeout(event *callback.Event, err error) error {
+	if event.Status.IsRetryable() && !event.NextRetryAt.IsZero() {
+		return fmt.Errorf("event %s is still %s, next attempt at %s: %w",
+			event.ID, event.Status, event.NextRetryAt.Format(time.RFC3339), err)
+	}
+	return fmt.Errorf("event %s is still %s: %w", event.ID, event.Status, err)
+}
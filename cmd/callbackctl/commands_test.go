@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"dev.azure.com/2f-capital/go-packages/callback-client.git/mock"
+)
+
+const testSecretKey = "test secret key"
+
+// newServer serves the event detail endpoint of the callback service from client.
+func newServer(t *testing.T, client callback.Client) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/event/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != testSecretKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(callback.ErrorResponse{CallbackError: callback.Error{Message: "unauthorized"}})
+			return
+		}
+
+		event, err := client.GetEventDetailByID(r.Context(), r.PathValue("id"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(callback.ErrorResponse{CallbackError: callback.Error{Message: err.Error()}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "data": event})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// sendEvent sends an event to a receiver answering statusCode and returns its id.
+func sendEvent(t *testing.T, client callback.Client, statusCode int, retryPolicy *callback.RetryPolicy) string {
+	t.Helper()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	defer receiver.Close()
+
+	// a failed delivery is reported as an error but the event is still recorded
+	_, _ = client.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   receiver.URL + "/callback",
+		WebhookSecret: "test webhook secret key",
+		Method:        http.MethodPost,
+		MaxRetries:    3,
+		RetryPolicy:   retryPolicy,
+	})
+
+	list, err := client.GetListOfEvents(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range list.Data {
+		if strings.HasSuffix(e.CallbackURL, receiver.URL+"/callback") {
+			return e.ID.String()
+		}
+	}
+	t.Fatalf("expected the mock to record the event sent to %s", receiver.URL)
+	return ""
+}
+
+func TestWait(t *testing.T) {
+	t.Setenv(envConfig, "")
+	t.Setenv(envOutput, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	client := mock.Init()
+	server := newServer(t, client)
+
+	succeeded := sendEvent(t, client, http.StatusOK, nil)
+	failed := sendEvent(t, client, http.StatusBadRequest, nil)
+	retrying := sendEvent(t, client, http.StatusInternalServerError, &callback.RetryPolicy{
+		Strategy:        callback.RetryStrategyFixed,
+		InitialInterval: time.Hour,
+	})
+
+	tests := []struct {
+		name       string
+		eventID    string
+		args       []string
+		wantCode   int
+		wantStatus callback.Status
+		wantErr    string
+	}{
+		{
+			name:       "succeeded event",
+			eventID:    succeeded,
+			wantStatus: callback.StatusSucceeded,
+		},
+		{
+			name:       "failed event",
+			eventID:    failed,
+			wantCode:   3,
+			wantStatus: callback.StatusFailed,
+		},
+		{
+			name:     "time out on retrying event",
+			eventID:  retrying,
+			args:     []string{"-timeout", "50ms", "-interval", "10ms"},
+			wantCode: 1,
+			wantErr:  "is still RETRYING, next attempt at",
+		},
+		{
+			name:     "reject wrong secret key",
+			eventID:  succeeded,
+			args:     []string{"-secret-key", "other secret key"},
+			wantCode: 1,
+			wantErr:  "unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"wait", "-url", server.URL, "-secret-key", testSecretKey, "-o", "json"}, tt.args...)
+			stdout, _, err := runCommand(t, "", append(args, tt.eventID)...)
+			if code := exitCodeOf(err); code != tt.wantCode {
+				t.Errorf("expected to get exit code %v, but got %v (%v)", tt.wantCode, code, err)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error to contain %q, but got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			var events []callback.Event
+			if err := json.Unmarshal([]byte(stdout), &events); err != nil {
+				t.Fatalf("expected json output, but got %q: %v", stdout, err)
+			}
+			if len(events) != 1 || events[0].ID.String() != tt.eventID || events[0].Status != tt.wantStatus {
+				t.Errorf("expected to get event %v with status %v, but got %+v", tt.eventID, tt.wantStatus, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	envURL       = "CALLBACK_URL"
+	envSecretKey = "CALLBACK_SECRET_KEY"
+	envOutput    = "CALLBACKCTL_OUTPUT"
+	envConfig    = "CALLBACKCTL_CONFIG"
+
+	envWebhookSecret = "CALLBACK_WEBHOOK_SECRET"
+)
+
+// config holds the settings shared by every command.
+// Flags take precedence over environment variables, which take precedence over the config file.
+type config struct {
+	// URL is the callback server url
+	URL string `json:"url"`
+	// SecretKey is the service secret key
+	SecretKey string `json:"secret_key"`
+	// Output is the output format, table or json
+	Output string `json:"output"`
+}
+
+// commonFlags registers the flags shared by every command and returns a function
+// that resolves the final config once the flags are parsed.
+func commonFlags(fs *flag.FlagSet) func() (*config, error) {
+	path := fs.String("config", "", "config file (default $"+envConfig+" or ~/.config/callbackctl/config.json)")
+	url := fs.String("url", "", "callback server url (default $"+envURL+")")
+	secretKey := fs.String("secret-key", "", "service secret key (default $"+envSecretKey+")")
+	output := fs.String("o", "", "output format, table or json (default $"+envOutput+" or table)")
+
+	return func() (*config, error) {
+		cfg, err := loadConfig(*path)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.URL = firstNonEmpty(*url, os.Getenv(envURL), cfg.URL)
+		cfg.SecretKey = firstNonEmpty(*secretKey, os.Getenv(envSecretKey), cfg.SecretKey)
+		cfg.Output = firstNonEmpty(*output, os.Getenv(envOutput), cfg.Output, outputTable)
+
+		if cfg.Output != outputTable && cfg.Output != outputJSON {
+			return nil, fmt.Errorf("unsupported output %q", cfg.Output)
+		}
+		return cfg, nil
+	}
+}
+
+// webhookSecretFlag registers the -webhook-secret flag and returns a function that
+// resolves it once the flags are parsed, falling back to $CALLBACK_WEBHOOK_SECRET.
+// The environment is only read after parsing so the usage never prints the secret.
+func webhookSecretFlag(fs *flag.FlagSet, usage string) func() string {
+	secret := fs.String("webhook-secret", "", usage+" (default $"+envWebhookSecret+")")
+	return func() string {
+		return firstNonEmpty(*secret, os.Getenv(envWebhookSecret))
+	}
+}
+
+// loadConfig reads the config file at path. Without a path, the default
+// config file is read if it exists.
+func loadConfig(path string) (*config, error) {
+	explicit := true
+	if path == "" {
+		path = os.Getenv(envConfig)
+	}
+	if path == "" {
+		explicit = false
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return &config{}, nil
+		}
+		path = filepath.Join(dir, "callbackctl", "config.json")
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c *config) validateClient() error {
+	if c.URL == "" {
+		return fmt.Errorf("callback server url is required, set -url or $%s", envURL)
+	}
+	if c.SecretKey == "" {
+		return fmt.Errorf("secret key is required, set -secret-key or $%s", envSecretKey)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommonFlagsPrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		want  config
+		isErr bool
+	}{
+		{
+			name: "default output without any source",
+			want: config{Output: outputTable},
+		},
+		{
+			name: "read config file",
+			file: `{"url":"http://file","secret_key":"file-key","output":"json"}`,
+			want: config{URL: "http://file", SecretKey: "file-key", Output: outputJSON},
+		},
+		{
+			name: "prefer env over config file",
+			file: `{"url":"http://file","secret_key":"file-key","output":"table"}`,
+			env:  map[string]string{envURL: "http://env", envSecretKey: "env-key", envOutput: "json"},
+			want: config{URL: "http://env", SecretKey: "env-key", Output: outputJSON},
+		},
+		{
+			name: "prefer flags over env and config file",
+			file: `{"url":"http://file","secret_key":"file-key","output":"json"}`,
+			env:  map[string]string{envURL: "http://env", envSecretKey: "env-key", envOutput: "json"},
+			args: []string{"-url", "http://flag", "-secret-key", "flag-key", "-o", "table"},
+			want: config{URL: "http://flag", SecretKey: "flag-key", Output: outputTable},
+		},
+		{
+			name: "fall back per setting",
+			file: `{"url":"http://file","secret_key":"file-key"}`,
+			env:  map[string]string{envSecretKey: "env-key"},
+			args: []string{"-o", "json"},
+			want: config{URL: "http://file", SecretKey: "env-key", Output: outputJSON},
+		},
+		{
+			name:  "reject unsupported output",
+			env:   map[string]string{envOutput: "yaml"},
+			isErr: true,
+		},
+		{
+			name:  "reject invalid config file",
+			file:  `{"url":`,
+			isErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			for _, name := range []string{envURL, envSecretKey, envOutput, envConfig} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if tt.file != "" {
+				path := filepath.Join(dir, "config.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv(envConfig, path)
+			}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			resolve := commonFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := resolve()
+			if (err != nil) != tt.isErr {
+				t.Errorf("expected error to be %v, but got %v", tt.isErr, err)
+				return
+			}
+			if err == nil && *cfg != tt.want {
+				t.Errorf("expected to get %+v, but got %+v", tt.want, *cfg)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExplicitPath(t *testing.T) {
+	t.Setenv(envConfig, "")
+
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing explicit config file, but got nil")
+	}
+}
+
+func TestWebhookSecretFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{name: "empty without any source"},
+		{name: "read env", env: "env-secret", want: "env-secret"},
+		{name: "prefer flag over env", env: "env-secret", args: []string{"-webhook-secret", "flag-secret"}, want: "flag-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envWebhookSecret, tt.env)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			resolve := webhookSecretFlag(fs, "webhook secret")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resolve(); got != tt.want {
+				t.Errorf("expected to get %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUsageHidesWebhookSecret(t *testing.T) {
+	t.Setenv(envWebhookSecret, "supersecret")
+
+	for _, command := range []string{"send", "verify", "listen"} {
+		t.Run(command, func(t *testing.T) {
+			_, stderr, _ := runCommand(t, "", command, "-h")
+			if !strings.Contains(stderr, "-webhook-secret") {
+				t.Errorf("expected usage to list -webhook-secret, but got %q", stderr)
+			}
+			if strings.Contains(stderr, "supersecret") {
+				t.Errorf("expected usage not to print the webhook secret, but got %q", stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
+)
+
+// listener receives signed callbacks on localhost, verifies and prints them
+// and optionally forwards the verified ones to a local app.
+type listener struct {
+	env           *env
+	output        string
+	secret        string
+	forwardTo     string
+	forwardSecret string
+	maxBody       int64
+	client        *http.Client
+
+	mu sync.Mutex
+}
+
+// listenResult is what the listener prints for each received callback.
+type listenResult struct {
+	ReceivedAt    time.Time       `json:"received_at"`
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	Timestamp     string          `json:"timestamp"`
+	EventID       string          `json:"event_id,omitempty"`
+	Attempt       string          `json:"attempt,omitempty"`
+	DeliveryID    string          `json:"delivery_id,omitempty"`
+	Verified      bool            `json:"verified"`
+	Error         string          `json:"error,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	ForwardedTo   string          `json:"forwarded_to,omitempty"`
+	ForwardStatus int             `json:"forward_status,omitempty"`
+	ForwardError  string          `json:"forward_error,omitempty"`
+}
+
+func runListen(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "listen", "")
+	addr := fs.String("addr", "127.0.0.1:4242", "loopback address to listen on")
+	resolve := commonFlags(fs)
+	resolveSecret := webhookSecretFlag(fs, "secret the callbacks are signed with")
+	forwardTo := fs.String("forward-to", "", "local app url verified callbacks are forwarded to")
+	forwardSecret := fs.String("forward-secret", "", "re-sign forwarded callbacks with this secret instead of keeping the original signature")
+	maxBody := fs.Int64("max-body", 1<<20, "maximum accepted body size in bytes")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	secret := resolveSecret()
+	if secret == "" {
+		return fmt.Errorf("webhook secret is required, set -webhook-secret or $%s", envWebhookSecret)
+	}
+	if err := requireLoopback(*addr); err != nil {
+		return err
+	}
+	if *forwardTo != "" {
+		if err := requireLoopbackURL(*forwardTo); err != nil {
+			return err
+		}
+	}
+
+	l := &listener{
+		env:           env,
+		output:        cfg.Output,
+		secret:        secret,
+		forwardTo:     *forwardTo,
+		forwardSecret: *forwardSecret,
+		maxBody:       *maxBody,
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           l,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(env.stderr, "listening for callbacks on http://%s\n", ln.Addr())
+	if *forwardTo != "" {
+		fmt.Fprintf(env.stderr, "forwarding verified callbacks to %s\n", *forwardTo)
+	}
+
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (l *listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result := listenResult{
+		ReceivedAt: time.Now(),
+		Method:     r.Method,
+		Path:       r.URL.RequestURI(),
+		Timestamp:  r.Header.Get(callbackreceiver.TimestampHeader),
+		EventID:    r.Header.Get(callbackreceiver.EventIDHeader),
+		Attempt:    r.Header.Get(callbackreceiver.AttemptHeader),
+		DeliveryID: r.Header.Get(callbackreceiver.DeliveryIDHeader),
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, l.maxBody))
+	if err != nil {
+		result.Error = err.Error()
+		l.print(result)
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if json.Valid(payload) {
+		result.Payload = payload
+	}
+
+	if _, err := callbackreceiver.NewVerifier(l.secret).VerifyHeader(r.Header, bytes.NewReader(payload)); err != nil {
+		result.Error = err.Error()
+		l.print(result)
+		http.Error(w, "signature verification failed", http.StatusUnauthorized)
+		return
+	}
+	result.Verified = true
+
+	if l.forwardTo == "" {
+		l.print(result)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	res, err := l.forward(r, payload)
+	result.ForwardedTo = l.forwardTo
+	if err != nil {
+		result.ForwardError = err.Error()
+		l.print(result)
+		http.Error(w, "forwarding failed", http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+
+	result.ForwardStatus = res.StatusCode
+	l.print(result)
+
+	// relay the app response so the sender sees what the app answered
+	for name, values := range res.Header {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = io.Copy(w, res.Body)
+}
+
+// forward sends the verified callback to the local app, re-signing it when a forward secret is set.
+func (l *listener) forward(r *http.Request, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, l.forwardTo, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+
+	if l.forwardSecret != "" {
+		signed, err := callbackreceiver.Sign(l.forwardSecret, payload, callbackreceiver.SignOptions{
+			Time:    time.Now(),
+			EventID: r.Header.Get(callbackreceiver.EventIDHeader),
+		})
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(callbackreceiver.SignatureHeader, signed.Get(callbackreceiver.SignatureHeader))
+		req.Header.Set(callbackreceiver.TimestampHeader, signed.Get(callbackreceiver.TimestampHeader))
+	}
+
+	return l.client.Do(req)
+}
+
+func (l *listener) print(result listenResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.output == outputJSON {
+		_ = json.NewEncoder(l.env.stdout).Encode(result)
+		return
+	}
+
+	w := l.env.stdout
+	fmt.Fprintf(w, "--> %s %s [%s]\n", result.Method, result.Path, result.ReceivedAt.Format(time.RFC3339))
+	if result.Verified {
+		fmt.Fprintf(w, "    signature: verified (X-MP-Time %s)\n", result.Timestamp)
+		if result.EventID != "" {
+			fmt.Fprintf(w, "    event: %s attempt %s delivery %s\n", result.EventID, result.Attempt, result.DeliveryID)
+		}
+	} else {
+		fmt.Fprintf(w, "    signature: FAILED: %s\n", result.Error)
+	}
+
+	if len(result.Payload) > 0 {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, result.Payload, "    ", "  "); err == nil {
+			fmt.Fprintf(w, "    payload: %s\n", pretty.String())
+		}
+	}
+
+	switch {
+	case result.ForwardError != "":
+		fmt.Fprintf(w, "    forward to %s: FAILED: %s\n", result.ForwardedTo, result.ForwardError)
+	case result.ForwardedTo != "":
+		fmt.Fprintf(w, "    forward to %s: %d %s\n", result.ForwardedTo, result.ForwardStatus, http.StatusText(result.ForwardStatus))
+	}
+	fmt.Fprintln(w)
+}
+
+// requireLoopback rejects listen addresses that are reachable from other hosts.
+func requireLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("address %s is not a loopback address", addr)
+	}
+	return nil
+}
+
+func requireLoopbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid forward url %s", rawURL)
+	}
+	if !isLoopbackHost(u.Hostname()) {
+		return fmt.Errorf("forward url %s is not on a loopback host", rawURL)
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
+	"dev.azure.com/2f-capital/go-packages/callback-client.git/mock"
+)
+
+func TestRequireLoopback(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "127.0.0.1:4242"},
+		{addr: "[::1]:4242"},
+		{addr: "localhost:4242"},
+		{addr: "0.0.0.0:4242", wantErr: true},
+		{addr: ":4242", wantErr: true},
+		{addr: "[::]:4242", wantErr: true},
+		{addr: "192.168.1.10:4242", wantErr: true},
+		{addr: "example.com:4242", wantErr: true},
+		{addr: "127.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := requireLoopback(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("expected error to be %v, but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRequireLoopbackURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "http://127.0.0.1:8080/callback"},
+		{url: "http://localhost:8080/callback"},
+		{url: "http://[::1]:8080/callback"},
+		{url: "http://10.0.0.5:8080/callback", wantErr: true},
+		{url: "https://example.com/callback", wantErr: true},
+		{url: "/callback", wantErr: true},
+		{url: "://invalid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := requireLoopbackURL(tt.url); (err != nil) != tt.wantErr {
+				t.Errorf("expected error to be %v, but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestListenRejectsNonLoopback(t *testing.T) {
+	t.Setenv(envConfig, "")
+	t.Setenv(envOutput, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "listen address", args: []string{"-addr", "0.0.0.0:0"}},
+		{name: "forward url", args: []string{"-addr", "127.0.0.1:0", "-forward-to", "http://10.0.0.5/callback"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"listen", "-webhook-secret", "test webhook secret key"}, tt.args...)
+			_, _, err := runCommand(t, "", args...)
+			if err == nil || !strings.Contains(err.Error(), "loopback") {
+				t.Errorf("expected a loopback error, but got %v", err)
+			}
+		})
+	}
+}
+
+// signedRequest returns a callback request to the listener signed with secret.
+func signedRequest(t *testing.T, payload, secret, eventID string) *http.Request {
+	t.Helper()
+
+	now := time.Now()
+	hash, err := mock.GenerateEventHashWithID([]byte(payload), secret, now, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(callbackreceiver.SignatureHeader, hash)
+	r.Header.Set(callbackreceiver.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	r.Header.Set(callbackreceiver.EventIDHeader, eventID)
+	return r
+}
+
+func TestListenerServeHTTP(t *testing.T) {
+	const (
+		secret        = "test webhook secret key"
+		forwardSecret = "local app secret"
+		eventID       = "8d7f5b0e-6f1a-4c1e-9a53-3f0f1c2b7d11"
+		payload       = `{"event":"payment_success"}`
+	)
+
+	// app only accepts callbacks signed with appSecret and echoes the payload with a 201
+	newApp := func(t *testing.T, appSecret string) *httptest.Server {
+		app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if _, err := callbackreceiver.NewVerifier(appSecret).VerifyHeader(r.Header, bytes.NewReader(body)); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-App", "ok")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		}))
+		t.Cleanup(app.Close)
+		return app
+	}
+
+	tests := []struct {
+		name          string
+		request       *http.Request
+		maxBody       int64
+		appSecret     string
+		forwardSecret string
+		wantCode      int
+		wantBody      string
+		wantOut       string
+	}{
+		{
+			name:     "accept valid signature",
+			request:  signedRequest(t, payload, secret, eventID),
+			wantCode: http.StatusOK,
+			wantOut:  `"verified":true`,
+		},
+		{
+			name:     "reject invalid signature",
+			request:  signedRequest(t, payload, "other secret", eventID),
+			wantCode: http.StatusUnauthorized,
+			wantOut:  `"verified":false`,
+		},
+		{
+			name:     "reject body over max body",
+			request:  signedRequest(t, payload, secret, eventID),
+			maxBody:  int64(len(payload) - 1),
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantOut:  `"verified":false`,
+		},
+		{
+			name:      "forward with original signature",
+			request:   signedRequest(t, payload, secret, eventID),
+			appSecret: secret,
+			wantCode:  http.StatusCreated,
+			wantBody:  payload,
+			wantOut:   `"forward_status":201`,
+		},
+		{
+			name:          "forward re-signed with forward secret",
+			request:       signedRequest(t, payload, secret, eventID),
+			appSecret:     forwardSecret,
+			forwardSecret: forwardSecret,
+			wantCode:      http.StatusCreated,
+			wantBody:      payload,
+			wantOut:       `"forward_status":201`,
+		},
+		{
+			name:      "relay app rejection of original signature",
+			request:   signedRequest(t, payload, secret, eventID),
+			appSecret: forwardSecret,
+			wantCode:  http.StatusUnauthorized,
+			wantOut:   `"forward_status":401`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			l := &listener{
+				env:           &env{stdout: &stdout, stderr: io.Discard},
+				output:        outputJSON,
+				secret:        secret,
+				forwardSecret: tt.forwardSecret,
+				maxBody:       1 << 20,
+				client:        &http.Client{Timeout: 5 * time.Second},
+			}
+			if tt.maxBody > 0 {
+				l.maxBody = tt.maxBody
+			}
+			if tt.appSecret != "" {
+				l.forwardTo = newApp(t, tt.appSecret).URL + "/callback"
+			}
+
+			w := httptest.NewRecorder()
+			l.ServeHTTP(w, tt.request)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected to get %v, but got %v", tt.wantCode, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("expected to get body %q, but got %q", tt.wantBody, w.Body.String())
+			}
+			if tt.wantCode == http.StatusCreated && w.Header().Get("X-App") != "ok" {
+				t.Errorf("expected the app response headers to be relayed, but got %v", w.Header())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("expected output to contain %q, but got %q", tt.wantOut, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Command callbackctl sends and inspects callback events of the callback service.
+//
+// Usage:
+//
+//	callbackctl <command> [flags]
+//
+// The commands are send, get, list, history, wait, verify and listen.
+// The server url and secret key are read from -url and -secret-key, the
+// CALLBACK_URL and CALLBACK_SECRET_KEY environment variables or a JSON config file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+)
+
+// errUsage is returned when the arguments are invalid, after the usage was printed.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+// env holds the streams commands read from and write to.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var commands = map[string]command{
+	"send":    {usage: "send a callback event, the payload is read from -payload or stdin", run: runSend},
+	"get":     {usage: "get the detail of an event", run: runGet},
+	"list":    {usage: "list events", run: runList},
+	"history": {usage: "list the callback attempts of an event", run: runHistory},
+	"wait":    {usage: "wait until an event reaches a terminal status", run: runWait},
+	"verify":  {usage: "verify the signature of a captured webhook", run: runVerify},
+	"listen":  {usage: "receive, verify and forward signed callbacks on localhost", run: runListen},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:])
+	if err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "callbackctl:", err)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+func run(ctx context.Context, env *env, args []string) error {
+	if len(args) == 0 {
+		usage(env.stderr)
+		return errUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "unknown command %q\n\n", args[0])
+		usage(env.stderr)
+		return errUsage
+	}
+	return cmd.run(ctx, env, args[1:])
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: callbackctl <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s%s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'callbackctl <command> -h' for the flags of a command.")
+}
+
+// newFlagSet returns the flag set of a command, args describes its positional arguments.
+func newFlagSet(env *env, name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "Usage: callbackctl %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// exitError is returned by commands that ran fine but must exit with a specific code.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
+
+func exitCode(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	if errors.Is(err, errUsage) {
+		return 2
+	}
+	return 1
+}
+
+// oneArg returns the single positional argument of fs.
+func oneArg(fs *flag.FlagSet, name string) (string, error) {
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+		fmt.Fprintf(fs.Output(), "expected exactly one %s argument\n", name)
+		fs.Usage()
+		return "", errUsage
+	}
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// runCommand runs callbackctl with args and returns what it wrote to stdout and stderr.
+func runCommand(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}, args)
+	return stdout.String(), stderr.String(), err
+}
+
+// exitCodeOf returns the exit code callbackctl exits with after err.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCode(err)
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command"},
+		{name: "unknown command", args: []string{"unknown"}},
+		{name: "unknown flag", args: []string{"get", "-unknown"}},
+		{name: "missing argument", args: []string{"get"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := runCommand(t, "", tt.args...)
+			if code := exitCodeOf(err); code != 2 {
+				t.Errorf("expected to get exit code %v, but got %v (%v)", 2, code, err)
+			}
+			if !strings.Contains(stderr, "Usage: callbackctl") {
+				t.Errorf("expected usage on stderr, but got %q", stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeEvents(w io.Writer, output string, events []callback.Event) error {
+	if output == outputJSON {
+		return writeJSON(w, events)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tMETHOD\tCALLBACK URL\tRETRIES\tLAST CODE\tNEXT RETRY\tCREATED")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d/%d\t%d\t%s\t%s\n",
+			e.ID, e.Status, e.Method, e.CallbackURL, e.RetryCount, e.MaxRetries,
+			e.LastResponseCode, formatTime(e.NextRetryAt), formatTime(e.CreatedAt))
+	}
+	return tw.Flush()
+}
+
+func writeHistory(w io.Writer, output string, history []callback.CallbackHistory) error {
+	if output == outputJSON {
+		return writeJSON(w, history)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tATTEMPT\tSTATUS\tCODE\tDURATION\tREASON\tCREATED")
+	for _, h := range history {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			h.ID, h.AttemptNumber, h.Status, h.ResponseCode, h.Duration, h.ReasonFailed, formatTime(h.CreatedAt))
+	}
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
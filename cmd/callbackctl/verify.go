@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
+)
+
+func runVerify(_ context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "verify", "")
+	resolve := commonFlags(fs)
+	resolveSecret := webhookSecretFlag(fs, "webhook secret")
+	requestPath := fs.String("request", "", "raw HTTP request dump of the webhook, - reads stdin")
+	payloadPath := fs.String("payload", "-", "payload file when -request is not set, - reads stdin")
+	signature := fs.String("signature", "", "X-MP-SIGNATURE header when -request is not set")
+	timestamp := fs.String("timestamp", "", "X-MP-Time header when -request is not set")
+	eventID := fs.String("event-id", "", "X-MP-Event-ID header when -request is not set, empty for legacy callbacks")
+	tolerance := fs.Duration("tolerance", 0, "reject timestamps further than this from now, 0 accepts captured webhooks of any age")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	secret := resolveSecret()
+	if secret == "" {
+		return fmt.Errorf("webhook secret is required, set -webhook-secret or $%s", envWebhookSecret)
+	}
+
+	var (
+		payload []byte
+		header  = http.Header{}
+	)
+	if *requestPath != "" {
+		payload, header, err = readCapturedRequest(env, *requestPath)
+	} else {
+		payload, err = readFile(env, *payloadPath)
+		header.Set(callbackreceiver.SignatureHeader, *signature)
+		header.Set(callbackreceiver.TimestampHeader, *timestamp)
+		header.Set(callbackreceiver.EventIDHeader, *eventID)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Verified  bool   `json:"verified"`
+		Timestamp string `json:"timestamp"`
+		EventID   string `json:"event_id,omitempty"`
+		Error     string `json:"error,omitempty"`
+	}{
+		Timestamp: header.Get(callbackreceiver.TimestampHeader),
+		EventID:   header.Get(callbackreceiver.EventIDHeader),
+	}
+
+	verifier := callbackreceiver.NewVerifier(secret, callbackreceiver.WithTolerance(*tolerance))
+	_, verifyErr := verifier.VerifyHeader(header, bytes.NewReader(payload))
+	result.Verified = verifyErr == nil
+	if verifyErr != nil {
+		result.Error = verifyErr.Error()
+	}
+
+	if cfg.Output == outputJSON {
+		err = writeJSON(env.stdout, result)
+	} else if result.Verified {
+		_, err = fmt.Fprintf(env.stdout, "signature verified (timestamp %s, event %s)\n", result.Timestamp, result.EventID)
+	} else {
+		_, err = fmt.Fprintf(env.stdout, "signature verification failed: %s\n", result.Error)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !result.Verified {
+		return &exitError{code: 3, msg: "signature verification failed"}
+	}
+	return nil
+}
+
+// readCapturedRequest reads a raw HTTP request and returns its body and headers.
+func readCapturedRequest(env *env, path string) ([]byte, http.Header, error) {
+	b, err := readFile(env, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid captured request: %w", err)
+	}
+	defer req.Body.Close()
+
+	payload, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, req.Header, nil
+}
+
+func readFile(env *env, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(env.stdin)
+	}
+	return os.ReadFile(path)
+}
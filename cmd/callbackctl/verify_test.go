@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"dev.azure.com/2f-capital/go-packages/callback-client.git/mock"
+)
+
+func TestVerify(t *testing.T) {
+	t.Setenv(envOutput, "")
+	t.Setenv(envConfig, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	const (
+		secret  = "test webhook secret key"
+		eventID = "8d7f5b0e-6f1a-4c1e-9a53-3f0f1c2b7d11"
+		payload = `{"event":"payment_success"}`
+	)
+	now := time.Now()
+	hash, err := mock.GenerateEventHashWithID([]byte(payload), secret, now, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	captured := fmt.Sprintf("POST /callback HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\n"+
+		"X-MP-SIGNATURE: %s\r\nX-MP-Time: %s\r\nX-MP-Event-ID: %s\r\nContent-Length: %d\r\n\r\n%s",
+		hash, timestamp, eventID, len(payload), payload)
+
+	headerArgs := []string{"-webhook-secret", secret, "-signature", hash, "-timestamp", timestamp, "-event-id", eventID}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		stdin    string
+		wantCode int
+		wantOut  string
+	}{
+		{
+			name:     "verify payload and headers",
+			args:     headerArgs,
+			stdin:    payload,
+			wantCode: 0,
+			wantOut:  "signature verified",
+		},
+		{
+			name:     "verify captured request",
+			args:     []string{"-webhook-secret", secret, "-request", "-"},
+			stdin:    captured,
+			wantCode: 0,
+			wantOut:  "signature verified",
+		},
+		{
+			name:     "reject tampered payload",
+			args:     headerArgs,
+			stdin:    `{"event":"payment_failed"}`,
+			wantCode: 3,
+			wantOut:  "signature verification failed",
+		},
+		{
+			name:     "reject wrong secret",
+			args:     []string{"-webhook-secret", "other secret", "-request", "-"},
+			stdin:    captured,
+			wantCode: 3,
+			wantOut:  "signature verification failed",
+		},
+		{
+			name:     "reject missing event id",
+			args:     []string{"-webhook-secret", secret, "-signature", hash, "-timestamp", timestamp},
+			stdin:    payload,
+			wantCode: 3,
+			wantOut:  "signature verification failed",
+		},
+		{
+			name:     "reject expired timestamp with tolerance",
+			args:     append([]string{"-tolerance", "1ns"}, headerArgs...),
+			stdin:    payload,
+			wantCode: 3,
+		},
+		{
+			name:     "require webhook secret",
+			env:      map[string]string{"CALLBACK_WEBHOOK_SECRET": ""},
+			args:     []string{"-request", "-"},
+			stdin:    captured,
+			wantCode: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			stdout, _, err := runCommand(t, tt.stdin, append([]string{"verify"}, tt.args...)...)
+			if code := exitCodeOf(err); code != tt.wantCode {
+				t.Errorf("expected to get exit code %v, but got %v (%v)", tt.wantCode, code, err)
+			}
+			if !strings.Contains(stdout, tt.wantOut) {
+				t.Errorf("expected output to contain %q, but got %q", tt.wantOut, stdout)
+			}
+		})
+	}
+}
+
+func TestVerifyJSONOutput(t *testing.T) {
+	t.Setenv(envConfig, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	const (
+		secret  = "test webhook secret key"
+		eventID = "8d7f5b0e-6f1a-4c1e-9a53-3f0f1c2b7d11"
+		payload = `{"event":"payment_success"}`
+	)
+	now := time.Now()
+	hash, err := mock.GenerateEventHashWithID([]byte(payload), secret, now, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name         string
+		output       string
+		args         []string
+		wantVerified bool
+		wantCode     int
+	}{
+		{
+			name:         "json flag on verified webhook",
+			args:         []string{"-o", "json", "-webhook-secret", secret},
+			wantVerified: true,
+		},
+		{
+			name:         "json env on verified webhook",
+			output:       outputJSON,
+			args:         []string{"-webhook-secret", secret},
+			wantVerified: true,
+		},
+		{
+			name:     "json flag on rejected webhook",
+			args:     []string{"-o", "json", "-webhook-secret", "other secret"},
+			wantCode: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envOutput, tt.output)
+
+			args := append([]string{"verify"}, tt.args...)
+			args = append(args, "-signature", hash, "-timestamp", timestamp, "-event-id", eventID)
+			stdout, _, err := runCommand(t, payload, args...)
+			if code := exitCodeOf(err); code != tt.wantCode {
+				t.Errorf("expected to get exit code %v, but got %v (%v)", tt.wantCode, code, err)
+			}
+
+			var result struct {
+				Verified  bool   `json:"verified"`
+				Timestamp string `json:"timestamp"`
+				EventID   string `json:"event_id"`
+				Error     string `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+				t.Fatalf("expected json output, but got %q: %v", stdout, err)
+			}
+			if result.Verified != tt.wantVerified {
+				t.Errorf("expected verified to be %v, but got %v", tt.wantVerified, result.Verified)
+			}
+			if result.Timestamp != timestamp || result.EventID != eventID {
+				t.Errorf("expected to get timestamp %v and event %v, but got %v and %v", timestamp, eventID, result.Timestamp, result.EventID)
+			}
+			if (result.Error != "") == tt.wantVerified {
+				t.Errorf("expected error only when not verified, but got %q", result.Error)
+			}
+		})
+	}
+}
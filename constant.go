@@ -7,15 +7,32 @@ const (
 	StatusBoolNO  StatusBool = "NO"
 )
 
+// Status is the delivery status of an event or a callback attempt.
 type Status string
 
 const (
 	StatusPending    Status = "PENDING"
-	StatusActive     Status = "ACTIVE"
-	StatusInactive   Status = "INACTIVE"
-	StatusFailed     Status = "FAILED"
-	StatusSucceeded  Status = "SUCCEEDED"
 	StatusProcessing Status = "PROCESSING"
+	StatusRetrying   Status = "RETRYING"
+	StatusSucceeded  Status = "SUCCEEDED"
+	StatusFailed     Status = "FAILED"
+)
+
+// ServiceStatus is the status of a service registered on the callback server.
+type ServiceStatus string
+
+const (
+	ServiceStatusActive   ServiceStatus = "ACTIVE"
+	ServiceStatusInactive ServiceStatus = "INACTIVE"
+)
+
+// StatusActive and StatusInactive are untyped so code comparing them with either a Status
+// or a ServiceStatus keeps compiling. Events are never ACTIVE anymore, see Status.UnmarshalJSON.
+const (
+	// Deprecated: use ServiceStatusActive for services and StatusPending for events.
+	StatusActive = "ACTIVE"
+	// Deprecated: use ServiceStatusInactive.
+	StatusInactive = "INACTIVE"
 )
 
 type Method string
@@ -27,3 +44,37 @@ const (
 	MethodPatch  Method = "PATCH"
 	MethodDelete Method = "DELETE"
 )
+
+// IsValid reports whether m is one of the supported callback methods.
+func (m Method) IsValid() bool {
+	switch m {
+	case MethodPost, MethodGet, MethodPut, MethodPatch, MethodDelete:
+		return true
+	}
+	return false
+}
+
+const (
+	// MinRetries is the lowest accepted value of CallbackRequestEvent.MaxRetries.
+	MinRetries int64 = 0
+	// MaxRetriesLimit is the highest accepted value of CallbackRequestEvent.MaxRetries.
+	MaxRetriesLimit int64 = 100
+)
+
+type RetryStrategy string
+
+const (
+	RetryStrategyFixed       RetryStrategy = "FIXED"
+	RetryStrategyExponential RetryStrategy = "EXPONENTIAL"
+	RetryStrategySchedule    RetryStrategy = "SCHEDULE"
+)
+
+// ResponseBodyLimit is the number of bytes of a receiver response kept in CallbackHistory.
+const ResponseBodyLimit = 4 << 10
+
+// SignatureHeaders are the request headers redacted from CallbackHistory.RequestHeaders.
+var SignatureHeaders = []string{
+	"X-MP-SIGNATURE",
+	"X-MP-Signature-Ed25519",
+	"webhook-signature",
+}
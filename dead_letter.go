@@ -0,0 +1,231 @@
+package callbackclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusQueryKey is the filter query parameter selecting events by status.
+const StatusQueryKey = "status"
+
+// DeadLetter is an event the callback service gave up delivering.
+type DeadLetter struct {
+	EventID          uuid.UUID `json:"event_id"`
+	CallbackURL      string    `json:"callback_url"`
+	ReasonFailed     string    `json:"reason_failed,omitempty"`
+	LastResponseCode int64     `json:"last_response_code,omitempty"`
+	RetryCount       int64     `json:"retry_count"`
+	MaxRetries       int64     `json:"max_retries"`
+	FailedAt         time.Time `json:"failed_at"`
+	// Event is the full dead-lettered event
+	Event Event `json:"-"`
+}
+
+func newDeadLetter(e Event) DeadLetter {
+	return DeadLetter{
+		EventID:          e.ID,
+		CallbackURL:      e.CallbackURL,
+		ReasonFailed:     e.ReasonFailed,
+		LastResponseCode: e.LastResponseCode,
+		RetryCount:       e.RetryCount,
+		MaxRetries:       e.MaxRetries,
+		FailedAt:         e.UpdatedAt,
+		Event:            e,
+	}
+}
+
+// ListDeadLetters pages through every failed event matching filter.
+func ListDeadLetters(ctx context.Context, client Client, filter string) ([]DeadLetter, error) {
+	query, err := url.ParseQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	query.Set(StatusQueryKey, string(StatusFailed))
+
+	var deadLetters []DeadLetter
+	err = EachEventPage(ctx, client, query.Encode(), 1, DefaultPerPage, func(_ int, list *EventList) error {
+		for _, e := range list.Data {
+			// the server filter is trusted but not relied on
+			if e.Status == StatusFailed {
+				deadLetters = append(deadLetters, newDeadLetter(e))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+type ReplayStatus string
+
+const (
+	ReplayStatusReplayed ReplayStatus = "REPLAYED"
+	ReplayStatusFailed   ReplayStatus = "FAILED"
+	ReplayStatusSkipped  ReplayStatus = "SKIPPED"
+	ReplayStatusDryRun   ReplayStatus = "DRY_RUN"
+)
+
+type ReplayOptions struct {
+	// Filter selects the dead letters to replay when EventIDs is empty
+	Filter string
+	// EventIDs are the events to replay, events that are not dead-lettered are skipped
+	EventIDs []string
+	// Concurrency is the number of events replayed at the same time, 1 by default
+	Concurrency int
+	// OverrideURL replaces the callback url of the replayed events
+	OverrideURL string
+	// DryRun reports what would be replayed without sending anything
+	DryRun bool
+}
+
+type ReplayResult struct {
+	EventID     string       `json:"event_id"`
+	CallbackURL string       `json:"callback_url,omitempty"`
+	Status      ReplayStatus `json:"status"`
+	// AcknowledgementID is the id of the new event created by the replay
+	AcknowledgementID uuid.UUID `json:"acknowledgement_id,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+type ReplayReport struct {
+	Results  []ReplayResult `json:"results"`
+	Replayed int            `json:"replayed"`
+	DryRun   int            `json:"dry_run"`
+	Failed   int            `json:"failed"`
+	Skipped  int            `json:"skipped"`
+}
+
+// ReplayDeadLetters sends the selected dead letters again as new events and reports the outcome of each.
+// Failed events are terminal, so a replay never changes the original event.
+func ReplayDeadLetters(ctx context.Context, client Client, opts ReplayOptions) (*ReplayReport, error) {
+	if opts.OverrideURL != "" {
+		if u, err := url.Parse(opts.OverrideURL); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid override url %s", opts.OverrideURL)
+		}
+	}
+
+	targets, err := replayTargets(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]ReplayResult, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if target.result != nil {
+			results[i] = *target.result
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, e Event) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = replay(ctx, client, e, opts)
+		}(i, target.event)
+	}
+	wg.Wait()
+
+	report := &ReplayReport{Results: results}
+	for _, r := range results {
+		switch r.Status {
+		case ReplayStatusReplayed:
+			report.Replayed++
+		case ReplayStatusDryRun:
+			report.DryRun++
+		case ReplayStatusFailed:
+			report.Failed++
+		case ReplayStatusSkipped:
+			report.Skipped++
+		}
+	}
+	return report, nil
+}
+
+// replayTarget is an event to replay, or the result of an event that can not be replayed.
+type replayTarget struct {
+	event  Event
+	result *ReplayResult
+}
+
+func replayTargets(ctx context.Context, client Client, opts ReplayOptions) ([]replayTarget, error) {
+	if len(opts.EventIDs) == 0 {
+		deadLetters, err := ListDeadLetters(ctx, client, opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+
+		targets := make([]replayTarget, 0, len(deadLetters))
+		for _, d := range deadLetters {
+			targets = append(targets, replayTarget{event: d.Event})
+		}
+		return targets, nil
+	}
+
+	targets := make([]replayTarget, 0, len(opts.EventIDs))
+	for _, id := range opts.EventIDs {
+		event, err := client.GetEventDetailByID(ctx, id)
+		switch {
+		case err != nil:
+			targets = append(targets, replayTarget{result: &ReplayResult{EventID: id, Status: ReplayStatusFailed, Error: err.Error()}})
+		case event.Status != StatusFailed:
+			targets = append(targets, replayTarget{result: &ReplayResult{
+				EventID:     id,
+				CallbackURL: event.CallbackURL,
+				Status:      ReplayStatusSkipped,
+				Error:       fmt.Sprintf("event is %s, not %s", event.Status, StatusFailed),
+			}})
+		default:
+			targets = append(targets, replayTarget{event: *event})
+		}
+	}
+	return targets, nil
+}
+
+func replay(ctx context.Context, client Client, e Event, opts ReplayOptions) ReplayResult {
+	result := ReplayResult{
+		EventID:     e.ID.String(),
+		CallbackURL: e.CallbackURL,
+	}
+	if opts.OverrideURL != "" {
+		result.CallbackURL = opts.OverrideURL
+	}
+
+	if opts.DryRun {
+		result.Status = ReplayStatusDryRun
+		return result
+	}
+
+	confirmation, err := client.SendCallbackEvent(ctx, CallbackRequestEvent{
+		ServiceID:     e.ServiceID,
+		Payload:       e.Payload,
+		CallbackURL:   result.CallbackURL,
+		WebhookSecret: e.WebhookSecret,
+		Method:        string(e.Method),
+		MaxRetries:    e.MaxRetries,
+		RetryPolicy:   e.RetryPolicy,
+	})
+	if err != nil {
+		result.Status = ReplayStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = ReplayStatusReplayed
+	result.AcknowledgementID = confirmation.AcknowledgementID
+	return result
+}
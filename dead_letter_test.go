@@ -0,0 +1,87 @@
+package callbackclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"dev.azure.com/2f-capital/go-packages/callback-client.git/mock"
+)
+
+func TestReplayDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := mock.Init()
+	send := func(path string) string {
+		confirmation, _ := client.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+			Payload:       map[string]interface{}{"event": "payment_success"},
+			CallbackURL:   server.URL + path,
+			WebhookSecret: "test webhook secret key",
+			Method:        http.MethodPost,
+		})
+		if confirmation == nil {
+			return ""
+		}
+		return confirmation.AcknowledgementID.String()
+	}
+	send("/fail")
+	send("/fail")
+	succeeded := send("/ok")
+
+	deadLetters, err := callback.ListDeadLetters(context.Background(), client, "")
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	if len(deadLetters) != 2 {
+		t.Fatalf("expected to get 2 dead letters, but got %d", len(deadLetters))
+	}
+	if deadLetters[0].LastResponseCode != http.StatusInternalServerError {
+		t.Errorf("expected last response code %d, but got %d", http.StatusInternalServerError, deadLetters[0].LastResponseCode)
+	}
+
+	tests := []struct {
+		name string
+		opts callback.ReplayOptions
+		want callback.ReplayReport
+	}{
+		{
+			name: "dry run does not send",
+			opts: callback.ReplayOptions{DryRun: true},
+			want: callback.ReplayReport{DryRun: 2},
+		},
+		{
+			name: "skip events that are not dead-lettered",
+			opts: callback.ReplayOptions{EventIDs: []string{deadLetters[0].EventID.String(), succeeded}, OverrideURL: server.URL + "/ok"},
+			want: callback.ReplayReport{Replayed: 1, Skipped: 1},
+		},
+		{
+			name: "replay to the original url fails again",
+			opts: callback.ReplayOptions{Concurrency: 2},
+			want: callback.ReplayReport{Failed: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := callback.ReplayDeadLetters(context.Background(), client, tt.opts)
+			if err != nil {
+				t.Errorf("expected to get nil error, but got %v", err)
+				return
+			}
+
+			if report.Replayed != tt.want.Replayed || report.DryRun != tt.want.DryRun ||
+				report.Failed != tt.want.Failed || report.Skipped != tt.want.Skipped {
+				t.Errorf("expected report %+v, but got %+v", tt.want, *report)
+			}
+		})
+	}
+}
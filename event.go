@@ -16,7 +16,12 @@ func (c *callbackClient) SendCallbackEvent(ctx context.Context, param CallbackRe
 		Data *CallbackServiceEventConfirmation `json:"data,omitempty"`
 	}
 
-	err := retry.Do(func() error {
+	body, err := param.MarshalRawJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	err = retry.Do(func() error {
 		if err := DoRequest(
 			ctx,
 			http.MethodPost,
@@ -24,7 +29,7 @@ func (c *callbackClient) SendCallbackEvent(ctx context.Context, param CallbackRe
 			func(r *http.Request) {
 				r.Header.Set("Authorization", c.SecretKey)
 			},
-			param,
+			body,
 			&successResponse,
 			errorResponse,
 		); err != nil {
@@ -1,6 +1,7 @@
 package callbackclient
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -17,16 +18,38 @@ type CallbackRequestEvent struct {
 	// CallbackURL is the endpoint where the event data will be sent
 	CallbackURL string `json:"callback_url,omitempty" example:"https://service.com/callback"`
 	// WebhookSecret is a security token used to verify the event source
-	WebhookSecret string `json:"webhook_secret,omitempty"`
+	WebhookSecret Secret `json:"webhook_secret,omitempty"`
 	// Method defines the HTTP request method (e.g., POST, PUT) used to send the callback
 	Method string `json:"method,omitempty" example:"POST"`
 	// MaxRetries specifies the maximum number of retry attempts if the callback fails
 	MaxRetries int64 `json:"max_retries,omitempty" example:"50"`
+	// RetryPolicy controls the delay between retries, the service default is used when nil
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
 }
 
+// MarshalRawJSON marshals the request with the actual webhook secret.
+// It is the wire format used to send the request to the callback server.
+func (c CallbackRequestEvent) MarshalRawJSON() ([]byte, error) {
+	type request CallbackRequestEvent
+	return json.Marshal(struct {
+		request
+		WebhookSecret string `json:"webhook_secret,omitempty"`
+	}{
+		request:       request(c),
+		WebhookSecret: c.WebhookSecret.Reveal(),
+	})
+}
+
+// Validate checks the request without restricting the callback url beyond its syntax.
 func (c CallbackRequestEvent) Validate() error {
-	return validation.ValidateStruct(&c,
-		validation.Field(&c.ServiceID,
+	return c.ValidateWithURLPolicy(URLPolicy{})
+}
+
+// ValidateWithURLPolicy checks the request and additionally validates the callback url against policy.
+// The returned error is a *ValidationError.
+func (c CallbackRequestEvent) ValidateWithURLPolicy(policy URLPolicy) error {
+	return newValidationError(validation.Errors{
+		"service_id": validation.Validate(c.ServiceID,
 			validation.Required.Error("service_id is required"),
 			validation.By(func(value interface{}) error {
 				id, ok := value.(uuid.UUID)
@@ -36,7 +59,7 @@ func (c CallbackRequestEvent) Validate() error {
 				return nil
 			}),
 		),
-		validation.Field(&c.Payload,
+		"payload": validation.Validate(c.Payload,
 			validation.Required.Error("payload is required"),
 			validation.By(func(value interface{}) error {
 				// check if the value is empty
@@ -48,12 +71,28 @@ func (c CallbackRequestEvent) Validate() error {
 				return nil
 			}),
 		),
-		validation.Field(&c.CallbackURL,
+		"callback_url": validation.Validate(c.CallbackURL,
 			validation.Required.Error("callback url is required"),
 			is.URL.Error("invalid callback url provided"),
+			validation.By(func(value interface{}) error {
+				return policy.Validate(value.(string))
+			}),
+		),
+		"webhook_secret": validation.Validate(c.WebhookSecret, validation.Required.Error("webhook secret is required")),
+		"method": validation.Validate(c.Method,
+			validation.By(func(value interface{}) error {
+				if m := Method(value.(string)); m != "" && !m.IsValid() {
+					return fmt.Errorf("method %q is not supported", m)
+				}
+				return nil
+			}),
 		),
-		validation.Field(&c.WebhookSecret, validation.Required.Error("webhook secret is required")),
-	)
+		"max_retries": validation.Validate(c.MaxRetries,
+			validation.Min(MinRetries).Error(fmt.Sprintf("max retries must be at least %d", MinRetries)),
+			validation.Max(MaxRetriesLimit).Error(fmt.Sprintf("max retries must be at most %d", MaxRetriesLimit)),
+		),
+		"retry_policy": validation.Validate(c.RetryPolicy),
+	})
 }
 
 type CallbackServiceEventConfirmation struct {
@@ -72,13 +111,15 @@ type Event struct {
 	// CallbackURL is the endpoint where the event data will be sent
 	CallbackURL string `json:"callback_url,omitempty" example:"https://service.com/callback"`
 	// WebhookSecret is a security token used to verify the event source
-	WebhookSecret string `json:"webhook_secret,omitempty"`
+	WebhookSecret Secret `json:"webhook_secret,omitempty"`
 	// Method defines the HTTP request method (e.g., POST, PUT) used to send the callback
 	Method Method `json:"method,omitempty" example:"POST"`
-	// Status indicates the current state of the event (e.g., ACTIVE, FAILED)
-	Status Status `json:"status,omitempty" example:"ACTIVE"`
+	// Status indicates the current delivery state of the event (e.g., RETRYING, FAILED)
+	Status Status `json:"status,omitempty" example:"SUCCEEDED"`
 	// MaxRetries specifies the maximum number of retry attempts if the callback fails
 	MaxRetries int64 `json:"max_retries,omitempty" example:"50"`
+	// RetryPolicy controls the delay between retries
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
 	// RetryCount tracks the number of times the event has been retried
 	RetryCount int64 `json:"retry_count,omitempty" example:"10"`
 	// NextRetryAt specifies the scheduled time for the next retry attempt
@@ -99,9 +140,9 @@ type Service struct {
 	// Name of the service
 	Name string `json:"name,omitempty" example:"Example Service"`
 	// Status of the service
-	Status Status `json:"status,omitempty" example:"ACTIVE"`
+	Status ServiceStatus `json:"status,omitempty" example:"ACTIVE"`
 	// Secret token (omitted in Swagger)
-	SecretToken string `json:"secret_token,omitempty" swaggerignore:"true"`
+	SecretToken Secret `json:"secret_token,omitempty" swaggerignore:"true"`
 	// CreatedAt when the service was created
 	CreatedAt time.Time `json:"created_at,omitempty" example:"2023-09-11T14:30:00Z"`
 	// UpdatedAt when the service was last updated
@@ -112,5 +153,3 @@ type EventList struct {
 	Data     []Event  `json:"data"`
 	MetaData MetaData `json:"meta_data,omitempty"`
 }
-
-
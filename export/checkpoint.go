@@ -0,0 +1,65 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint records how far an export got.
+type Checkpoint struct {
+	// Page is the first event page that was not completely exported
+	Page int `json:"page"`
+	// EventID is the last exported event on Page, empty if none was exported
+	EventID string `json:"event_id,omitempty"`
+	// Rows is the number of rows written so far
+	Rows int64 `json:"rows"`
+}
+
+// IsStart reports whether the checkpoint is at the start of an export.
+func (c Checkpoint) IsStart() bool {
+	return c.Page <= 1 && c.EventID == "" && c.Rows == 0
+}
+
+// LoadCheckpoint reads a checkpoint saved by SaveCheckpoint.
+// It returns nil without an error if the file does not exist.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(b, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint atomically writes the checkpoint to path.
+func SaveCheckpoint(path string, checkpoint Checkpoint) error {
+	b, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+// PayloadColumn is the column holding the event payload.
+// Single payload values are selected with "payload.<dotted key>" columns.
+const PayloadColumn = "payload"
+
+// row is an event joined with one of its delivery attempts.
+// attempt is nil for events without any attempt.
+type row struct {
+	event   *callback.Event
+	attempt *callback.CallbackHistory
+}
+
+type column func(r row) interface{}
+
+var columns = map[string]column{
+	"event_id":           func(r row) interface{} { return r.event.ID.String() },
+	"service_id":         func(r row) interface{} { return r.event.ServiceID.String() },
+	"callback_url":       func(r row) interface{} { return r.event.CallbackURL },
+	"webhook_secret":     func(r row) interface{} { return r.event.WebhookSecret.String() },
+	"method":             func(r row) interface{} { return string(r.event.Method) },
+	"status":             func(r row) interface{} { return string(r.event.Status) },
+	"max_retries":        func(r row) interface{} { return r.event.MaxRetries },
+	"retry_count":        func(r row) interface{} { return r.event.RetryCount },
+	"next_retry_at":      func(r row) interface{} { return formatTime(r.event.NextRetryAt) },
+	"last_response_code": func(r row) interface{} { return r.event.LastResponseCode },
+	"reason_failed":      func(r row) interface{} { return r.event.ReasonFailed },
+	"created_at":         func(r row) interface{} { return formatTime(r.event.CreatedAt) },
+	"updated_at":         func(r row) interface{} { return formatTime(r.event.UpdatedAt) },
+
+	"attempt_id":            attemptColumn(func(a *callback.CallbackHistory) interface{} { return a.ID.String() }),
+	"attempt_number":        attemptColumn(func(a *callback.CallbackHistory) interface{} { return a.AttemptNumber }),
+	"attempt_status":        attemptColumn(func(a *callback.CallbackHistory) interface{} { return string(a.Status) }),
+	"attempt_response_code": attemptColumn(func(a *callback.CallbackHistory) interface{} { return a.ResponseCode }),
+	"attempt_reason_failed": attemptColumn(func(a *callback.CallbackHistory) interface{} { return a.ReasonFailed }),
+	"attempt_duration_ms":   attemptColumn(func(a *callback.CallbackHistory) interface{} { return a.Duration.Milliseconds() }),
+	"attempt_target_url":    attemptColumn(func(a *callback.CallbackHistory) interface{} { return a.TargetURL }),
+	"attempt_created_at":    attemptColumn(func(a *callback.CallbackHistory) interface{} { return formatTime(a.CreatedAt) }),
+}
+
+// DefaultColumns are the columns exported when Options.Columns is empty.
+var DefaultColumns = []string{
+	"event_id",
+	"callback_url",
+	"method",
+	"status",
+	"retry_count",
+	"last_response_code",
+	"reason_failed",
+	"created_at",
+	"attempt_id",
+	"attempt_number",
+	"attempt_status",
+	"attempt_response_code",
+	"attempt_reason_failed",
+	"attempt_duration_ms",
+	"attempt_created_at",
+	PayloadColumn,
+}
+
+func attemptColumn(fn func(a *callback.CallbackHistory) interface{}) column {
+	return func(r row) interface{} {
+		if r.attempt == nil {
+			return nil
+		}
+		return fn(r.attempt)
+	}
+}
+
+func formatTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func isPayloadKey(name string) bool {
+	return strings.HasPrefix(name, PayloadColumn+".")
+}
+
+func validateColumn(name string) error {
+	if _, ok := columns[name]; ok || name == PayloadColumn || isPayloadKey(name) {
+		return nil
+	}
+	return fmt.Errorf("unknown column %q", name)
+}
+
+// flatten returns the payload as a map of dotted keys to scalar values.
+func flatten(payload map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, nested := range v {
+				walk(prefix+"."+key, nested)
+			}
+		case []interface{}:
+			for i, nested := range v {
+				walk(prefix+"."+strconv.Itoa(i), nested)
+			}
+		default:
+			flat[prefix] = v
+		}
+	}
+	walk(PayloadColumn, payload)
+	return flat
+}
+
+// lookupPayload returns the payload value at a dotted key such as "payload.customer.id".
+func lookupPayload(payload map[string]interface{}, name string) interface{} {
+	var value interface{} = payload
+	for _, key := range strings.Split(strings.TrimPrefix(name, PayloadColumn+"."), ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// flatKeys returns the keys of a flattened payload in a stable order.
+func flatKeys(flat map[string]interface{}) []string {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatValue formats a payload value as a cell.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
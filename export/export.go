@@ -0,0 +1,286 @@
+// Package export streams callback events and their delivery attempts to NDJSON or CSV.
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+type PayloadMode string
+
+const (
+	// PayloadJSON keeps the payload as a single JSON column
+	PayloadJSON PayloadMode = "json"
+	// PayloadFlatten spreads the payload over "payload.<dotted key>" columns
+	PayloadFlatten PayloadMode = "flatten"
+)
+
+// ErrCheckpointNotFound is returned when the event of a checkpoint is no longer on its page.
+var ErrCheckpointNotFound = errors.New("checkpoint event not found on its page")
+
+type Options struct {
+	// Format of the output, NDJSON by default
+	Format Format
+	// Columns to export in order, DefaultColumns by default.
+	// With PayloadFlatten, the payload column expands to every payload key in NDJSON
+	// while CSV needs the payload keys to be listed as "payload.<dotted key>" columns.
+	Columns []string
+	// Payload selects how the payload column is written, PayloadJSON by default
+	Payload PayloadMode
+	// Filter is the query string passed to GetListOfEvents, without pagination
+	Filter string
+	// HistoryFilter is the query string passed to GetCallbackHistoryByEventID, without pagination
+	HistoryFilter string
+	// PerPage is the page size used to list events and history
+	PerPage int
+	// Checkpoint resumes an interrupted export, nil starts from the first page
+	Checkpoint *Checkpoint
+	// OnCheckpoint is called with the progress after every exported event
+	OnCheckpoint func(Checkpoint) error
+}
+
+// Exporter writes one row per delivery attempt, joined with its event.
+// Events without attempts are written as a single row with empty attempt columns.
+type Exporter struct {
+	client callback.Client
+	opts   Options
+}
+
+func NewExporter(client callback.Client, opts Options) (*Exporter, error) {
+	if opts.Format == "" {
+		opts.Format = FormatNDJSON
+	}
+	if opts.Format != FormatNDJSON && opts.Format != FormatCSV {
+		return nil, fmt.Errorf("unsupported format %q", opts.Format)
+	}
+
+	if opts.Payload == "" {
+		opts.Payload = PayloadJSON
+	}
+	if opts.Payload != PayloadJSON && opts.Payload != PayloadFlatten {
+		return nil, fmt.Errorf("unsupported payload mode %q", opts.Payload)
+	}
+
+	if len(opts.Columns) == 0 {
+		opts.Columns = DefaultColumns
+	}
+	for _, name := range opts.Columns {
+		if err := validateColumn(name); err != nil {
+			return nil, err
+		}
+		if name == PayloadColumn && opts.Format == FormatCSV && opts.Payload == PayloadFlatten {
+			return nil, fmt.Errorf("csv export with a flattened payload needs %s.<key> columns instead of %s", PayloadColumn, PayloadColumn)
+		}
+	}
+
+	return &Exporter{
+		client: client,
+		opts:   opts,
+	}, nil
+}
+
+// Export writes every event matching the filter with its attempts to w.
+// It returns the last checkpoint reached, which can be passed back in Options to resume after an error.
+func (e *Exporter) Export(ctx context.Context, w io.Writer) (Checkpoint, error) {
+	checkpoint := Checkpoint{Page: 1}
+	if e.opts.Checkpoint != nil {
+		checkpoint = *e.opts.Checkpoint
+	}
+
+	out := e.newRecordWriter(w)
+	if checkpoint.IsStart() {
+		if err := out.writeHeader(e.opts.Columns); err != nil {
+			return checkpoint, err
+		}
+	}
+
+	skipTo := checkpoint.EventID
+	err := callback.EachEventPage(ctx, e.client, e.opts.Filter, checkpoint.Page, e.opts.PerPage, func(page int, list *callback.EventList) error {
+		for i := range list.Data {
+			event := &list.Data[i]
+			if skipTo != "" {
+				if event.ID.String() == skipTo {
+					skipTo = ""
+				}
+				continue
+			}
+
+			rows, err := e.rows(ctx, event)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range rows {
+				if err := out.write(e.record(r)); err != nil {
+					return err
+				}
+			}
+			if err := out.flush(); err != nil {
+				return err
+			}
+
+			checkpoint.Page = page
+			checkpoint.EventID = event.ID.String()
+			checkpoint.Rows += int64(len(rows))
+			if err := e.saveCheckpoint(checkpoint); err != nil {
+				return err
+			}
+		}
+
+		if skipTo != "" {
+			return ErrCheckpointNotFound
+		}
+
+		checkpoint.Page = page + 1
+		checkpoint.EventID = ""
+		return e.saveCheckpoint(checkpoint)
+	})
+
+	return checkpoint, err
+}
+
+func (e *Exporter) saveCheckpoint(checkpoint Checkpoint) error {
+	if e.opts.OnCheckpoint == nil {
+		return nil
+	}
+	return e.opts.OnCheckpoint(checkpoint)
+}
+
+// rows returns the event joined with each of its attempts.
+func (e *Exporter) rows(ctx context.Context, event *callback.Event) ([]row, error) {
+	var rows []row
+	err := callback.EachCallbackHistoryPage(ctx, e.client, event.ID.String(), e.opts.HistoryFilter, 1, e.opts.PerPage,
+		func(_ int, list *callback.CallbackHistoryList) error {
+			for i := range list.Data {
+				rows = append(rows, row{event: event, attempt: &list.Data[i]})
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		rows = append(rows, row{event: event})
+	}
+	return rows, nil
+}
+
+type field struct {
+	name  string
+	value interface{}
+}
+
+// record returns the selected columns of a row in order.
+func (e *Exporter) record(r row) []field {
+	var flat map[string]interface{}
+	if e.opts.Payload == PayloadFlatten {
+		flat = flatten(r.event.Payload)
+	}
+
+	record := make([]field, 0, len(e.opts.Columns))
+	for _, name := range e.opts.Columns {
+		switch {
+		case name == PayloadColumn && flat != nil:
+			for _, key := range flatKeys(flat) {
+				record = append(record, field{name: key, value: flat[key]})
+			}
+		case name == PayloadColumn:
+			record = append(record, field{name: name, value: r.event.Payload})
+		case isPayloadKey(name):
+			record = append(record, field{name: name, value: lookupPayload(r.event.Payload, name)})
+		default:
+			record = append(record, field{name: name, value: columns[name](r)})
+		}
+	}
+	return record
+}
+
+type recordWriter interface {
+	writeHeader(columns []string) error
+	write(record []field) error
+	flush() error
+}
+
+func (e *Exporter) newRecordWriter(w io.Writer) recordWriter {
+	if e.opts.Format == FormatCSV {
+		return &csvWriter{w: csv.NewWriter(w)}
+	}
+	return &ndjsonWriter{w: w}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) writeHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) write(record []field) error {
+	cells := make([]string, 0, len(record))
+	for _, f := range record {
+		cells = append(cells, formatValue(f.value))
+	}
+	return c.w.Write(cells)
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (n *ndjsonWriter) writeHeader([]string) error {
+	return nil
+}
+
+// write encodes the record as a JSON object keeping the column order.
+func (n *ndjsonWriter) write(record []field) error {
+	n.buf.WriteByte('{')
+	for i, f := range record {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return err
+		}
+
+		n.buf.Write(name)
+		n.buf.WriteByte(':')
+		n.buf.Write(value)
+	}
+	n.buf.WriteString("}\n")
+	return nil
+}
+
+func (n *ndjsonWriter) flush() error {
+	_, err := n.w.Write(n.buf.Bytes())
+	n.buf.Reset()
+	return err
+}
@@ -0,0 +1,164 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"dev.azure.com/2f-capital/go-packages/callback-client.git/mock"
+)
+
+func initTestClient(t *testing.T, events int) callback.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	client := mock.Init()
+	for i := 0; i < events; i++ {
+		_, err := client.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+			Payload: map[string]interface{}{
+				"event":    "payment_success",
+				"customer": map[string]interface{}{"id": i},
+			},
+			CallbackURL:   server.URL,
+			WebhookSecret: "test webhook secret key",
+			Method:        http.MethodPost,
+		})
+		if err != nil {
+			t.Fatalf("expected to get nil error, but got %v", err)
+		}
+	}
+	return client
+}
+
+func TestExport(t *testing.T) {
+	client := initTestClient(t, 3)
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "export ndjson with flattened payload",
+			opts: Options{
+				Columns: []string{"event_id", "webhook_secret", "attempt_number", PayloadColumn},
+				Payload: PayloadFlatten,
+				PerPage: 2,
+			},
+			want: []string{`"webhook_secret":"[REDACTED]"`, `"attempt_number":1`, `"payload.customer.id":2`, `"payload.event":"payment_success"`},
+		},
+		{
+			name: "export csv with payload keys",
+			opts: Options{
+				Format:  FormatCSV,
+				Columns: []string{"status", "payload.customer.id"},
+				Payload: PayloadFlatten,
+				PerPage: 2,
+			},
+			want: []string{"status,payload.customer.id\n", "SUCCEEDED,0\n", "SUCCEEDED,2\n"},
+		},
+		{
+			name: "reject flattened payload column in csv",
+			opts: Options{
+				Format:  FormatCSV,
+				Payload: PayloadFlatten,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "reject unknown column",
+			opts:    Options{Columns: []string{"unknown"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := NewExporter(client, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error to be %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var out bytes.Buffer
+			checkpoint, err := exporter.Export(context.Background(), &out)
+			if err != nil {
+				t.Errorf("expected to get nil error, but got %v", err)
+				return
+			}
+
+			if checkpoint.Rows != 3 {
+				t.Errorf("expected to export 3 rows, but got %d", checkpoint.Rows)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected output to contain %s, but got\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	client := initTestClient(t, 5)
+	errInterrupted := errors.New("interrupted")
+
+	var full bytes.Buffer
+	exporter, err := NewExporter(client, Options{Columns: []string{"event_id"}, PerPage: 2})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	if _, err := exporter.Export(context.Background(), &full); err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	var resumed bytes.Buffer
+	exported := 0
+	exporter, err = NewExporter(client, Options{
+		Columns: []string{"event_id"},
+		PerPage: 2,
+		OnCheckpoint: func(c Checkpoint) error {
+			if exported++; exported == 2 {
+				return errInterrupted
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	checkpoint, err := exporter.Export(context.Background(), &resumed)
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("expected to get %v, but got %v", errInterrupted, err)
+	}
+
+	exporter, err = NewExporter(client, Options{Columns: []string{"event_id"}, PerPage: 2, Checkpoint: &checkpoint})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+	checkpoint, err = exporter.Export(context.Background(), &resumed)
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	if resumed.String() != full.String() {
+		t.Errorf("expected resumed export to be\n%s\nbut got\n%s", full.String(), resumed.String())
+	}
+
+	if checkpoint.Rows != 5 {
+		t.Errorf("expected to export 5 rows, but got %d", checkpoint.Rows)
+	}
+}
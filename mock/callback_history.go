@@ -3,37 +3,51 @@ package mock
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
 	"github.com/google/uuid"
 )
 
 func (c *callbackClient) GetCallbackHistoryByEventID(ctx context.Context, eventID, filter string) (*callback.CallbackHistoryList, error) {
-	for _, e := range c.Service.Events {
-		if e.ID == eventID {
-			var callbackHistory []callback.CallbackHistory
-			if len(e.CallbackHistory) == 0 {
-				return &callback.CallbackHistoryList{Data: []callback.CallbackHistory{}}, nil
-			}
+	c.mu.RLock()
+	e, ok := c.Service.Events[eventID]
+	if !ok {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("callback history with eventID %s not found", eventID)
+	}
+
+	callbackHistory := []callback.CallbackHistory{}
+	for _, ch := range e.CallbackHistory {
+		callbackHistory = append(callbackHistory, callback.CallbackHistory{
+			ID:                    uuid.MustParse(ch.ID),
+			Event:                 *eventDetail(e),
+			Status:                callback.Status(ch.Status),
+			ResponseCode:          ch.ResponseCode,
+			ReasonFailed:          ch.ReasonFailed,
+			CreatedAt:             ch.CreatedAt,
+			AttemptNumber:         ch.AttemptNumber,
+			Duration:              ch.Duration,
+			TargetURL:             ch.TargetURL,
+			RequestHeaders:        ch.RequestHeaders.Clone(),
+			ResponseHeaders:       ch.ResponseHeaders.Clone(),
+			ResponseBody:          ch.ResponseBody,
+			ResponseBodyTruncated: ch.ResponseBodyTruncated,
+		})
+	}
+	c.mu.RUnlock()
 
-			event, err := c.GetEventDetailByID(ctx, eventID)
-			if err != nil {
-				return nil, err
-			}
-			for _, ch := range e.CallbackHistory {
-				callbackHistory = append(callbackHistory, callback.CallbackHistory{
-					ID:           uuid.MustParse(ch.ID),
-					Event:        *event,
-					Status:       callback.Status(ch.Status),
-					ResponseCode: ch.ResponseCode,
-					ReasonFailed: ch.ReasonFailed,
-					CreatedAt:    ch.CreatedAt,
-				})
-			}
+	sort.Slice(callbackHistory, func(i, j int) bool {
+		return callbackHistory[i].AttemptNumber < callbackHistory[j].AttemptNumber
+	})
 
-			return &callback.CallbackHistoryList{Data: callbackHistory}, nil
-		}
+	start, end, err := paginate(filter, len(callbackHistory))
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("callback history with eventID %s not found", eventID)
+	return &callback.CallbackHistoryList{
+		Data:     callbackHistory[start:end],
+		MetaData: callback.MetaData{Total: len(callbackHistory)},
+	}, nil
 }
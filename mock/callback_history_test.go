@@ -0,0 +1,79 @@
+package mock
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+func TestGetCallbackHistoryByEventID(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+	cb := callbackClient{
+		Service: Service{
+			Status: callback.ServiceStatusActive,
+			Events: make(map[string]*Event),
+		},
+	}
+
+	confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+	})
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+
+	history, err := cb.GetCallbackHistoryByEventID(context.Background(), confirmation.AcknowledgementID.String(), "")
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+
+	if len(history.Data) != 1 {
+		t.Errorf("expected to get 1 attempt, but got %d", len(history.Data))
+		return
+	}
+
+	attempt := history.Data[0]
+	if attempt.AttemptNumber != 1 {
+		t.Errorf("expected attempt number 1, but got %d", attempt.AttemptNumber)
+	}
+
+	if attempt.TargetURL != server.URL+"/v1/callback" {
+		t.Errorf("expected target url %s, but got %s", server.URL+"/v1/callback", attempt.TargetURL)
+	}
+
+	if attempt.Duration <= 0 {
+		t.Errorf("expected positive duration, but got %v", attempt.Duration)
+	}
+
+	if got := attempt.RequestHeaders.Get("X-MP-SIGNATURE"); got != "[REDACTED]" {
+		t.Errorf("expected redacted signature, but got %s", got)
+	}
+
+	if got := attempt.RequestHeaders.Get("X-MP-Time"); got == "" {
+		t.Errorf("expected X-MP-Time header to be recorded")
+	}
+
+	if got := attempt.RequestHeaders.Get("X-MP-Event-ID"); got != confirmation.AcknowledgementID.String() {
+		t.Errorf("expected X-MP-Event-ID %s, but got %s", confirmation.AcknowledgementID, got)
+	}
+
+	if got := attempt.RequestHeaders.Get("X-MP-Attempt"); got != "1" {
+		t.Errorf("expected X-MP-Attempt 1, but got %s", got)
+	}
+
+	if got := attempt.RequestHeaders.Get("X-MP-Delivery-ID"); got != attempt.ID.String() {
+		t.Errorf("expected X-MP-Delivery-ID %s, but got %s", attempt.ID, got)
+	}
+
+	if got := attempt.ResponseHeaders.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected response content type application/json, but got %s", got)
+	}
+}
@@ -1,13 +1,29 @@
 package mock
 
 import (
+	"crypto/ed25519"
+	"net/http"
+	"sync"
 	"time"
 
 	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
 )
 
+// callbackClient is a callback.Client delivering callbacks in memory, safe for concurrent use.
+// mu guards Service and the events in it.
 type callbackClient struct {
-	Service Service
+	mu                sync.RWMutex
+	Service           Service
+	signingKey        *signingKey
+	signatureVersions []SignatureVersion
+	standardWebhooks  bool
+	jitter            func() float64
+}
+
+// signingKey is the Ed25519 key callbacks are signed with.
+type signingKey struct {
+	id  string
+	key ed25519.PrivateKey
 }
 
 type Service struct {
@@ -16,11 +32,14 @@ type Service struct {
 	ID string `json:"id,omitempty"`
 	// Status is the current status of the service.
 	// It is set to active by default.
-	Status callback.Status `json:"status,omitempty"`
+	Status callback.ServiceStatus `json:"status,omitempty"`
 	// SecretToken is the secret the service uses to authenticate itself.
 	// It is automatically generated when the service is created.
 	SecretToken string `json:"secret_token,omitempty"`
 	Events      map[string]*Event
+	// Unsubscribed holds the callback urls whose receiver responded 410 Gone.
+	// No callback is sent to them anymore.
+	Unsubscribed map[string]bool
 }
 
 type Event struct {
@@ -34,10 +53,12 @@ type Event struct {
 	WebhookSecret string `json:"webhook_secret,omitempty"`
 	// Method defines the HTTP request method (e.g., POST, PUT) used to send the callback
 	Method callback.Method `json:"method,omitempty" example:"POST"`
-	// Status indicates the current state of the event (e.g., ACTIVE, FAILED)
-	Status callback.Status `json:"status,omitempty" example:"ACTIVE"`
+	// Status indicates the current delivery state of the event (e.g., RETRYING, FAILED)
+	Status callback.Status `json:"status,omitempty" example:"SUCCEEDED"`
 	// MaxRetries specifies the maximum number of retry attempts if the callback fails
 	MaxRetries int64 `json:"max_retries,omitempty" example:"50"`
+	// RetryPolicy controls the delay between retries
+	RetryPolicy *callback.RetryPolicy `json:"retry_policy,omitempty"`
 	// RetryCount tracks the number of times the event has been retried
 	RetryCount int64 `json:"retry_count,omitempty" example:"10"`
 	// NextRetryAt specifies the scheduled time for the next retry attempt
@@ -62,6 +83,20 @@ type CallbackHistory struct {
 	ResponseCode int64 `json:"response_code,omitempty" example:"200"`
 	// reason stores an error for failed callback attempt
 	ReasonFailed string `json:"reason_failed,omitempty"`
+	// AttemptNumber is the position of this attempt among the deliveries of the event, starting at 1
+	AttemptNumber int64 `json:"attempt_number,omitempty" example:"1"`
+	// Duration is the time between sending the callback and receiving the response
+	Duration time.Duration `json:"duration,omitempty"`
+	// TargetURL is the url the callback was sent to
+	TargetURL string `json:"target_url,omitempty"`
+	// RequestHeaders are the headers sent with the callback, with signatures redacted
+	RequestHeaders http.Header `json:"request_headers,omitempty"`
+	// ResponseHeaders are the headers returned by the receiver
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	// ResponseBody is the body returned by the receiver, truncated to callback.ResponseBodyLimit bytes
+	ResponseBody string `json:"response_body,omitempty"`
+	// ResponseBodyTruncated is true if ResponseBody was truncated
+	ResponseBodyTruncated bool `json:"response_body_truncated,omitempty"`
 	// CreatedAt when the callback history was created
 	CreatedAt time.Time `json:"created_at,omitempty" example:"2023-09-11T14:30:00Z" format:"date-time"`
 }
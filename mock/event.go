@@ -5,154 +5,286 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"time"
 
 	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
 	"github.com/google/uuid"
 )
 
 func (c *callbackClient) SendCallbackEvent(ctx context.Context, param callback.CallbackRequestEvent) (*callback.CallbackServiceEventConfirmation, error) {
 	eventData := &Event{
 		ID:              uuid.NewString(),
-		Payload:         param.Payload,
+		Payload:         copyPayload(param.Payload),
 		CallbackURL:     param.CallbackURL,
-		WebhookSecret:   param.WebhookSecret,
+		WebhookSecret:   param.WebhookSecret.Reveal(),
 		Method:          callback.Method(param.Method),
-		Status:          callback.StatusActive,
+		Status:          callback.StatusPending,
 		MaxRetries:      param.MaxRetries,
+		RetryPolicy:     copyRetryPolicy(param.RetryPolicy),
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 		CallbackHistory: make(map[string]*CallbackHistory),
 	}
+
+	c.mu.Lock()
+	if c.Service.Unsubscribed[param.CallbackURL] {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("callback url %s unsubscribed", param.CallbackURL)
+	}
+	if c.Service.Events == nil {
+		c.Service.Events = make(map[string]*Event)
+	}
 	c.Service.Events[eventData.ID] = eventData
+	c.mu.Unlock()
 
-	ht := time.Now()
-	payload, err := json.Marshal(param.Payload)
-	if err != nil {
+	if err := c.deliver(ctx, eventData); err != nil {
 		return nil, err
 	}
 
-	hash, err := GenerateEventHash(payload, param.WebhookSecret, ht)
+	eventID, err := uuid.Parse(eventData.ID)
 	if err != nil {
 		return nil, err
 	}
+	response := &callback.CallbackServiceEventConfirmation{
+		AcknowledgementID: eventID,
+	}
+	return response, nil
+}
+
+// deliver sends the event to its callback url once, records the attempt in its callback history
+// and updates its delivery state from the response of the receiver:
+// a 2xx acknowledges the event, a 410 fails it and unsubscribes its callback url,
+// any other 4xx fails it permanently and a 429 or 503 with a Retry-After header retries it after that delay.
+// Other failures are retried with the retry policy of the event.
+// The lock of c is only held while the event is read or updated, not during the request.
+func (c *callbackClient) deliver(ctx context.Context, e *Event) error {
+	// the callback is built and signed before the event moves to PROCESSING,
+	// so an event that can not be signed is left in its status instead of stuck in PROCESSING
+	c.mu.RLock()
+	method, secret := e.Method, e.WebhookSecret
+	payload, err := json.Marshal(e.Payload)
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	opts := callbackreceiver.SignOptions{
+		Time:     time.Now(),
+		EventID:  e.ID,
+		Versions: receiverVersions(c.signatureVersions),
+		Standard: c.standardWebhooks,
+	}
+	if c.signingKey != nil {
+		opts.KeyID, opts.Ed25519Key = c.signingKey.id, c.signingKey.key
+	}
+	signed, err := callbackreceiver.Sign(secret, payload, opts)
+	if err != nil {
+		return err
+	}
 
+	c.mu.Lock()
+	if err := e.transition(callback.StatusProcessing); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	attempt := &CallbackHistory{
+		ID:            uuid.NewString(),
+		AttemptNumber: e.RetryCount + 1,
+		TargetURL:     e.CallbackURL,
+	}
+	c.mu.Unlock()
+
+	start := time.Now()
 	res, err := DoRequest(
 		ctx,
-		param.Method,
-		param.CallbackURL,
+		string(method),
+		attempt.TargetURL,
 		"application/json",
 		func(r *http.Request) {
 			r.Header.Set("Content-Type", "application/json")
-			r.Header.Set("X-MP-SIGNATURE", hash)
-			r.Header.Set("X-MP-Time", fmt.Sprintf("%d", ht.Unix()))
+			for name, values := range signed {
+				r.Header[name] = values
+			}
+			r.Header.Set(callbackreceiver.AttemptHeader, strconv.FormatInt(attempt.AttemptNumber, 10))
+			r.Header.Set(callbackreceiver.DeliveryIDHeader, attempt.ID)
+			attempt.RequestHeaders = redactSignatures(r.Header)
 		},
-		param.Payload,
+		payload,
 		nil,
 	)
+	attempt.Duration = time.Since(start)
 	if res != nil {
+		attempt.ResponseCode = int64(res.StatusCode)
+		attempt.ResponseHeaders = res.Header.Clone()
+		attempt.ResponseBody, attempt.ResponseBodyTruncated = readResponseSnapshot(res.Body)
 		res.Body.Close()
-	}
 
-	if err != nil {
-		callbackHistory := &CallbackHistory{
-			ID:           uuid.NewString(),
-			Status:       string(callback.StatusFailed),
-			ReasonFailed: err.Error(),
-			CreatedAt:    time.Now(),
-		}
-		c.Service.Events[eventData.ID].CallbackHistory[callbackHistory.ID] = callbackHistory
-		c.Service.Events[eventData.ID].ReasonFailed = err.Error()
-		c.Service.Events[eventData.ID].Status = callback.StatusFailed
-		c.Service.Events[eventData.ID].RetryCount++
-		c.Service.Events[eventData.ID].UpdatedAt = time.Now()
-		return nil, err
-	} else if res.StatusCode != http.StatusOK {
-		err := fmt.Errorf("webhook rejected by service with statuscode %d", res.StatusCode)
-		callbackHistory := &CallbackHistory{
-			ID:           uuid.NewString(),
-			Status:       string(callback.StatusFailed),
-			ResponseCode: int64(res.StatusCode),
-			ReasonFailed: err.Error(),
-			CreatedAt:    time.Now(),
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			err = fmt.Errorf("webhook rejected by service with statuscode %d", res.StatusCode)
 		}
-		c.Service.Events[eventData.ID].CallbackHistory[callbackHistory.ID] = callbackHistory
-		c.Service.Events[eventData.ID].ReasonFailed = err.Error()
-		c.Service.Events[eventData.ID].Status = callback.StatusFailed
-		c.Service.Events[eventData.ID].LastResponseCode = int64(res.StatusCode)
-		c.Service.Events[eventData.ID].RetryCount++
-		c.Service.Events[eventData.ID].UpdatedAt = time.Now()
-		return nil, err
 	}
 
-	c.Service.Events[eventData.ID].Status = callback.StatusSucceeded
-	c.Service.Events[eventData.ID].LastResponseCode = int64(res.StatusCode)
-	c.Service.Events[eventData.ID].RetryCount++
-	c.Service.Events[eventData.ID].UpdatedAt = time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	callbackHistory := &CallbackHistory{
-		ID:           uuid.NewString(),
-		Status:       string(callback.StatusSucceeded),
-		ResponseCode: int64(res.StatusCode),
-		CreatedAt:    time.Now(),
+	if res != nil {
+		e.LastResponseCode = attempt.ResponseCode
+		if res.StatusCode == http.StatusGone {
+			if c.Service.Unsubscribed == nil {
+				c.Service.Unsubscribed = make(map[string]bool)
+			}
+			c.Service.Unsubscribed[e.CallbackURL] = true
+		}
 	}
-	c.Service.Events[eventData.ID].CallbackHistory[callbackHistory.ID] = callbackHistory
 
-	eventID, err := uuid.Parse(eventData.ID)
+	e.RetryCount++
+	e.UpdatedAt = time.Now()
+	attempt.CreatedAt = e.UpdatedAt
+	e.CallbackHistory[attempt.ID] = attempt
+
 	if err != nil {
-		return nil, err
+		attempt.Status = string(callback.StatusFailed)
+		attempt.ReasonFailed = err.Error()
+		e.ReasonFailed = err.Error()
+		retry := c.scheduleRetry(e, int(attempt.ResponseCode), attempt.ResponseHeaders.Get("Retry-After"))
+		if terr := e.transition(failedStatus(retry)); terr != nil {
+			return terr
+		}
+		return err
 	}
-	response := &callback.CallbackServiceEventConfirmation{
-		AcknowledgementID: eventID,
+
+	attempt.Status = string(callback.StatusSucceeded)
+	return e.transition(callback.StatusSucceeded)
+}
+
+// transition moves the event to status next, rejecting transitions the delivery state machine does not allow.
+func (e *Event) transition(next callback.Status) error {
+	if !e.Status.CanTransitionTo(next) {
+		return fmt.Errorf("invalid status transition from %s to %s", e.Status, next)
 	}
-	return response, nil
+
+	e.Status = next
+	return nil
 }
 
-func (c *callbackClient) GetEventDetailByID(ctx context.Context, eventID string) (*callback.Event, error) {
-	for _, e := range c.Service.Events {
-		if e.ID == eventID {
-			event := &callback.Event{
-				ID:               uuid.MustParse(e.ID),
-				Payload:          e.Payload,
-				CallbackURL:      e.CallbackURL,
-				WebhookSecret:    e.WebhookSecret,
-				Method:           e.Method,
-				Status:           e.Status,
-				MaxRetries:       e.MaxRetries,
-				RetryCount:       e.RetryCount,
-				NextRetryAt:      e.NextRetryAt,
-				LastResponseCode: e.LastResponseCode,
-				ReasonFailed:     e.ReasonFailed,
-				CreatedAt:        e.CreatedAt,
-				UpdatedAt:        e.UpdatedAt,
-			}
-			return event, nil
+// failedStatus returns the status of an event whose delivery attempt failed.
+func failedStatus(retry bool) callback.Status {
+	if retry {
+		return callback.StatusRetrying
+	}
+	return callback.StatusFailed
+}
+
+// scheduleRetry sets NextRetryAt of a failed event, relative to UpdatedAt, and reports whether a retry was scheduled.
+// A 4xx other than 429 is never retried. A 429 or 503 is retried after its retryAfter header when present,
+// even without a retry policy, other failures after the backoff of the retry policy.
+// Without WithJitterSource the mock applies no jitter so NextRetryAt is predictable in tests.
+func (c *callbackClient) scheduleRetry(e *Event, responseCode int, retryAfter string) bool {
+	e.NextRetryAt = time.Time{}
+	if e.RetryCount > e.MaxRetries {
+		return false
+	}
+
+	if isPermanentFailure(responseCode) {
+		return false
+	}
+
+	if e.RetryPolicy != nil && responseCode != 0 && !e.RetryPolicy.IsRetryableStatus(responseCode) {
+		return false
+	}
+
+	if responseCode == http.StatusTooManyRequests || responseCode == http.StatusServiceUnavailable {
+		if d, ok := parseRetryAfter(retryAfter, e.UpdatedAt); ok {
+			e.NextRetryAt = e.UpdatedAt.Add(d)
+			return true
 		}
 	}
 
-	return nil, fmt.Errorf("event not found")
+	if e.RetryPolicy == nil {
+		return false
+	}
+
+	backoff := e.RetryPolicy.Backoff(e.RetryCount)
+	if c.jitter != nil {
+		backoff = e.RetryPolicy.ApplyJitter(backoff, c.jitter())
+	}
+	e.NextRetryAt = e.UpdatedAt.Add(backoff)
+	return true
+}
+
+// isPermanentFailure reports whether the receiver rejected a callback for good with responseCode,
+// any 4xx but 429 Too Many Requests.
+func isPermanentFailure(responseCode int) bool {
+	return responseCode >= 400 && responseCode < 500 && responseCode != http.StatusTooManyRequests
+}
+
+func (c *callbackClient) GetEventDetailByID(ctx context.Context, eventID string) (*callback.Event, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.Service.Events[eventID]
+	if !ok {
+		return nil, fmt.Errorf("event not found")
+	}
+	return eventDetail(e), nil
 }
 
 func (c *callbackClient) GetListOfEvents(ctx context.Context, filter string) (*callback.EventList, error) {
 	var events []callback.Event
 
+	query, err := url.ParseQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
 	for _, e := range c.Service.Events {
-		event := callback.Event{
-			ID:               uuid.MustParse(e.ID),
-			Payload:          e.Payload,
-			CallbackURL:      e.CallbackURL,
-			WebhookSecret:    e.WebhookSecret,
-			Method:           e.Method,
-			Status:           e.Status,
-			MaxRetries:       e.MaxRetries,
-			RetryCount:       e.RetryCount,
-			NextRetryAt:      e.NextRetryAt,
-			LastResponseCode: e.LastResponseCode,
-			ReasonFailed:     e.ReasonFailed,
-			CreatedAt:        e.CreatedAt,
-			UpdatedAt:        e.UpdatedAt,
+		if status := query.Get(callback.StatusQueryKey); status != "" && string(e.Status) != status {
+			continue
 		}
-		events = append(events, event)
+		events = append(events, *eventDetail(e))
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.Before(events[j].CreatedAt)
+		}
+		return events[i].ID.String() < events[j].ID.String()
+	})
+
+	start, end, err := paginate(filter, len(events))
+	if err != nil {
+		return nil, err
+	}
+
+	return &callback.EventList{
+		Data:     events[start:end],
+		MetaData: callback.MetaData{Total: len(events)},
+	}, nil
+}
+
+// eventDetail returns a deep copy of e as a callback.Event, so callers cannot change the mock state through it.
+// The caller holds the lock of the client.
+func eventDetail(e *Event) *callback.Event {
+	return &callback.Event{
+		ID:               uuid.MustParse(e.ID),
+		Payload:          copyPayload(e.Payload),
+		CallbackURL:      e.CallbackURL,
+		WebhookSecret:    callback.Secret(e.WebhookSecret),
+		Method:           e.Method,
+		Status:           e.Status,
+		MaxRetries:       e.MaxRetries,
+		RetryPolicy:      copyRetryPolicy(e.RetryPolicy),
+		RetryCount:       e.RetryCount,
+		NextRetryAt:      e.NextRetryAt,
+		LastResponseCode: e.LastResponseCode,
+		ReasonFailed:     e.ReasonFailed,
+		CreatedAt:        e.CreatedAt,
+		UpdatedAt:        e.UpdatedAt,
 	}
-	return &callback.EventList{Data: events}, nil
 }
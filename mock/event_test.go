@@ -2,9 +2,12 @@ package mock
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +17,7 @@ import (
 	"time"
 
 	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
 	"github.com/google/uuid"
 )
 
@@ -24,6 +28,16 @@ func InitTestCallbackServer() *httptest.Server {
 		switch strings.TrimSpace(r.URL.Path) {
 		case "/v1/callback":
 			verifyCallbacks(w, r)
+		case "/v1/accepted":
+			w.WriteHeader(http.StatusAccepted)
+		case "/v1/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/v1/gone":
+			callbackreceiver.Unsubscribe(w)
+		case "/v1/throttled":
+			callbackreceiver.SlowDown(w, 2*time.Minute)
+		case "/v1/unavailable":
+			callbackreceiver.RetryLater(w, 0)
 		default:
 			http.NotFoundHandler().ServeHTTP(w, r)
 		}
@@ -35,6 +49,7 @@ func InitTestCallbackServer() *httptest.Server {
 func verifyCallbacks(w http.ResponseWriter, r *http.Request) {
 	hash := r.Header.Get("X-MP-SIGNATURE")
 	t := r.Header.Get("X-MP-Time")
+	eventID := r.Header.Get("X-MP-Event-ID")
 
 	payload, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -52,6 +67,10 @@ func verifyCallbacks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err := mac.Write([]byte(eventID + ".")); err != nil {
+		return
+	}
+
 	if _, err := mac.Write([]byte(payload)); err != nil {
 		return
 	}
@@ -59,6 +78,7 @@ func verifyCallbacks(w http.ResponseWriter, r *http.Request) {
 	expectedHash := hex.EncodeToString(mac.Sum(nil))
 
 	if expectedHash != hash {
+		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
@@ -71,7 +91,7 @@ func TestSendCallbackEvent(t *testing.T) {
 	defer server.Close()
 	cb := callbackClient{
 		Service: Service{
-			Status: callback.StatusActive,
+			Status: callback.ServiceStatusActive,
 			Events: make(map[string]*Event),
 		},
 	}
@@ -98,7 +118,7 @@ func TestSendCallbackEvent(t *testing.T) {
 						"status":         "completed",
 					},
 					CallbackURL:   server.URL + "/v1/callback",
-					WebhookSecret: secretKey,
+					WebhookSecret: callback.Secret(secretKey),
 					Method:        http.MethodPost,
 				},
 			},
@@ -127,7 +147,7 @@ func TestSendCallbackEvent(t *testing.T) {
 func TestGetEventDetailByID(t *testing.T) {
 	cb := callbackClient{
 		Service: Service{
-			Status: callback.StatusActive,
+			Status: callback.ServiceStatusActive,
 			Events: make(map[string]*Event),
 		},
 	}
@@ -167,7 +187,7 @@ func TestGetEventDetailByID(t *testing.T) {
 				ID:               uuid.MustParse(mockEvent.ID),
 				Payload:          mockEvent.Payload,
 				CallbackURL:      mockEvent.CallbackURL,
-				WebhookSecret:    mockEvent.WebhookSecret,
+				WebhookSecret:    callback.Secret(mockEvent.WebhookSecret),
 				Method:           mockEvent.Method,
 				Status:           mockEvent.Status,
 				RetryCount:       mockEvent.RetryCount,
@@ -192,3 +212,538 @@ func TestGetEventDetailByID(t *testing.T) {
 		}
 	}
 }
+
+func TestSendCallbackEventRetrySchedule(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		maxRetries  int64
+		retryPolicy *callback.RetryPolicy
+		want        time.Duration
+		wantRetry   bool
+		wantStatus  callback.Status
+	}{
+		{
+			name:       "schedule first retry with exponential policy",
+			path:       "/v1/error",
+			maxRetries: 5,
+			retryPolicy: &callback.RetryPolicy{
+				Strategy:        callback.RetryStrategyExponential,
+				InitialInterval: 10 * time.Second,
+				MaxInterval:     time.Minute,
+			},
+			want:       10 * time.Second,
+			wantRetry:  true,
+			wantStatus: callback.StatusRetrying,
+		},
+		{
+			name:       "schedule first retry with explicit schedule",
+			path:       "/v1/error",
+			maxRetries: 5,
+			retryPolicy: &callback.RetryPolicy{
+				Strategy: callback.RetryStrategySchedule,
+				Schedule: []time.Duration{time.Minute, 5 * time.Minute},
+			},
+			want:       time.Minute,
+			wantRetry:  true,
+			wantStatus: callback.StatusRetrying,
+		},
+		{
+			name:       "do not retry non retryable status code",
+			path:       "/v1/error",
+			maxRetries: 5,
+			retryPolicy: &callback.RetryPolicy{
+				Strategy:                callback.RetryStrategyFixed,
+				InitialInterval:         time.Second,
+				NonRetryableStatusCodes: []int{http.StatusInternalServerError},
+			},
+			wantRetry:  false,
+			wantStatus: callback.StatusFailed,
+		},
+		{
+			name:       "do not retry callback rejected permanently",
+			path:       "/v1/unknown",
+			maxRetries: 5,
+			retryPolicy: &callback.RetryPolicy{
+				Strategy:        callback.RetryStrategyFixed,
+				InitialInterval: time.Second,
+			},
+			wantRetry:  false,
+			wantStatus: callback.StatusFailed,
+		},
+		{
+			name:       "retry throttled callback after retry after",
+			path:       "/v1/throttled",
+			maxRetries: 5,
+			want:       2 * time.Minute,
+			wantRetry:  true,
+			wantStatus: callback.StatusRetrying,
+		},
+		{
+			name:       "retry unavailable receiver with policy without retry after",
+			path:       "/v1/unavailable",
+			maxRetries: 5,
+			retryPolicy: &callback.RetryPolicy{
+				Strategy:        callback.RetryStrategyFixed,
+				InitialInterval: 30 * time.Second,
+			},
+			want:       30 * time.Second,
+			wantRetry:  true,
+			wantStatus: callback.StatusRetrying,
+		},
+		{
+			name:       "do not retry throttled callback without retries left",
+			path:       "/v1/throttled",
+			wantRetry:  false,
+			wantStatus: callback.StatusFailed,
+		},
+		{
+			name: "do not retry without retries left",
+			path: "/v1/error",
+			retryPolicy: &callback.RetryPolicy{
+				Strategy:        callback.RetryStrategyFixed,
+				InitialInterval: time.Second,
+			},
+			wantRetry:  false,
+			wantStatus: callback.StatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := callbackClient{
+				Service: Service{
+					Status: callback.ServiceStatusActive,
+					Events: make(map[string]*Event),
+				},
+			}
+
+			_, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+				Payload:       map[string]interface{}{"event": "payment_success"},
+				CallbackURL:   server.URL + tt.path,
+				WebhookSecret: callback.Secret(secretKey),
+				Method:        http.MethodPost,
+				MaxRetries:    tt.maxRetries,
+				RetryPolicy:   tt.retryPolicy,
+			})
+			if err == nil {
+				t.Errorf("expected delivery to fail")
+				return
+			}
+
+			for _, e := range cb.Service.Events {
+				if e.Status != tt.wantStatus {
+					t.Errorf("expected status %s, but got %s", tt.wantStatus, e.Status)
+					return
+				}
+
+				if got := !e.NextRetryAt.IsZero(); got != tt.wantRetry {
+					t.Errorf("expected retry scheduled to be %v, but got %v", tt.wantRetry, got)
+					return
+				}
+
+				if got := e.NextRetryAt.Sub(e.UpdatedAt); tt.wantRetry && got != tt.want {
+					t.Errorf("expected next retry after %v, but got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSendCallbackEventRetryJitter(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+	cb := &callbackClient{
+		Service: Service{
+			Status: callback.ServiceStatusActive,
+			Events: make(map[string]*Event),
+		},
+	}
+	WithJitterSource(func() float64 { return 0 })(cb)
+
+	_, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/error",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+		MaxRetries:    5,
+		RetryPolicy: &callback.RetryPolicy{
+			Strategy:        callback.RetryStrategyFixed,
+			InitialInterval: time.Minute,
+			Jitter:          0.5,
+		},
+	})
+	if err == nil {
+		t.Errorf("expected delivery to fail")
+		return
+	}
+
+	for _, e := range cb.Service.Events {
+		if got := e.NextRetryAt.Sub(e.UpdatedAt); got != 30*time.Second {
+			t.Errorf("expected next retry after %v, but got %v", 30*time.Second, got)
+		}
+	}
+}
+
+func TestSendCallbackEventAcknowledged(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+	cb := Init()
+
+	confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/accepted",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+	})
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+
+	event, err := cb.GetEventDetailByID(context.Background(), confirmation.AcknowledgementID.String())
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if event.Status != callback.StatusSucceeded {
+		t.Errorf("expected status %s, but got %s", callback.StatusSucceeded, event.Status)
+	}
+}
+
+func TestSendCallbackEventUnsubscribe(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+	cb := callbackClient{
+		Service: Service{
+			Status: callback.ServiceStatusActive,
+			Events: make(map[string]*Event),
+		},
+	}
+	param := callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/gone",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+		MaxRetries:    5,
+		RetryPolicy: &callback.RetryPolicy{
+			Strategy:        callback.RetryStrategyFixed,
+			InitialInterval: time.Second,
+		},
+	}
+
+	if _, err := cb.SendCallbackEvent(context.Background(), param); err == nil {
+		t.Errorf("expected delivery to fail")
+		return
+	}
+
+	for _, e := range cb.Service.Events {
+		if e.Status != callback.StatusFailed {
+			t.Errorf("expected status %s, but got %s", callback.StatusFailed, e.Status)
+		}
+		if !e.NextRetryAt.IsZero() {
+			t.Errorf("expected no retry, but got one at %v", e.NextRetryAt)
+		}
+	}
+
+	if !cb.Service.Unsubscribed[param.CallbackURL] {
+		t.Errorf("expected %s to be unsubscribed", param.CallbackURL)
+	}
+
+	if _, err := cb.SendCallbackEvent(context.Background(), param); err == nil {
+		t.Errorf("expected sending to an unsubscribed url to fail")
+	}
+	if len(cb.Service.Events) != 1 {
+		t.Errorf("expected to get 1 event, but got %d", len(cb.Service.Events))
+	}
+}
+
+func TestSendCallbackEventStaleTimestamp(t *testing.T) {
+	// the receiver clock is ahead of the sender, so every timestamp is out of its tolerance window
+	verifier := callbackreceiver.NewVerifier(secretKey, callbackreceiver.WithClock(func() time.Time {
+		return time.Now().Add(time.Hour)
+	}))
+	server := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected stale callback to be rejected")
+	})))
+	defer server.Close()
+
+	cb := Init()
+	if _, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+		MaxRetries:    5,
+		RetryPolicy: &callback.RetryPolicy{
+			Strategy:        callback.RetryStrategyFixed,
+			InitialInterval: time.Minute,
+		},
+	}); err == nil {
+		t.Fatalf("expected delivery to fail")
+	}
+
+	list, err := cb.GetListOfEvents(context.Background(), "")
+	if err != nil || len(list.Data) != 1 {
+		t.Fatalf("expected to get 1 event, but got %v, %v", list, err)
+	}
+	e := list.Data[0]
+	if e.LastResponseCode != http.StatusServiceUnavailable {
+		t.Errorf("expected to get %v, but got %v", http.StatusServiceUnavailable, e.LastResponseCode)
+	}
+	if e.Status != callback.StatusRetrying || e.NextRetryAt.IsZero() {
+		t.Errorf("expected a retry to be scheduled, but got status %s and next retry %v", e.Status, e.NextRetryAt)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "parse seconds", header: "120", want: 2 * time.Minute, wantOk: true},
+		{name: "parse http date", header: "Mon, 01 Jan 2024 12:10:00 GMT", want: 10 * time.Minute, wantOk: true},
+		{name: "clamp past http date", header: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0, wantOk: true},
+		{name: "reject missing header", header: ""},
+		{name: "reject negative seconds", header: "-1"},
+		{name: "reject malformed header", header: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.header, now)
+			if ok != tt.wantOk {
+				t.Errorf("expected ok to be %v, but got %v", tt.wantOk, ok)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("expected to get %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEventTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    callback.Status
+		to      callback.Status
+		wantErr bool
+	}{
+		{name: "start processing pending event", from: callback.StatusPending, to: callback.StatusProcessing},
+		{name: "retry processing event", from: callback.StatusProcessing, to: callback.StatusRetrying},
+		{name: "reject leaving succeeded event", from: callback.StatusSucceeded, to: callback.StatusProcessing, wantErr: true},
+		{name: "reject skipping processing", from: callback.StatusPending, to: callback.StatusSucceeded, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Event{Status: tt.from}
+			err := e.transition(tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error to be %v, but got %v", tt.wantErr, err)
+				return
+			}
+
+			want := tt.to
+			if tt.wantErr {
+				want = tt.from
+			}
+
+			if e.Status != want {
+				t.Errorf("expected status %s, but got %s", want, e.Status)
+			}
+		})
+	}
+}
+
+func TestSendCallbackEventEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	verifier := callbackreceiver.NewVerifier("", callbackreceiver.WithPublicKeys(callbackreceiver.StaticKeySet{"key-1": pub}))
+	var webhook *callbackreceiver.Webhook
+	server := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhook, _ = callbackreceiver.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer server.Close()
+
+	cb := Init(WithSigningKey("key-1", priv))
+	confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+	})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	if webhook == nil {
+		t.Fatalf("expected callback to be verified with the Ed25519 key")
+	}
+	if webhook.KeyID != "key-1" || webhook.EventID != confirmation.AcknowledgementID.String() {
+		t.Errorf("expected key-1 and event %s, but got %s and %s", confirmation.AcknowledgementID, webhook.KeyID, webhook.EventID)
+	}
+}
+
+func TestSendCallbackEventSignatureVersions(t *testing.T) {
+	verifier := callbackreceiver.NewVerifier(secretKey, callbackreceiver.WithLegacySignatures(false))
+	var webhook *callbackreceiver.Webhook
+	var header string
+	server := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhook, _ = callbackreceiver.FromContext(r.Context())
+		header = r.Header.Get("X-MP-SIGNATURE")
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer server.Close()
+
+	cb := Init(WithSignatureVersions(SignatureV1, SignatureV2))
+	_, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+	})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	if webhook == nil || webhook.SignatureVersion != callbackreceiver.SignatureV2 {
+		t.Fatalf("expected callback to be verified with v2, but got %+v", webhook)
+	}
+	if !strings.HasPrefix(header, "t="+webhook.Timestamp+",v1=") || !strings.Contains(header, ",v2=") {
+		t.Errorf("expected versioned signature header, but got %s", header)
+	}
+}
+
+func TestSendCallbackEventVerifyEventHash(t *testing.T) {
+	var verifyErr, legacyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		hash, ts := r.Header.Get("X-MP-SIGNATURE"), r.Header.Get("X-MP-Time")
+		_, verifyErr = callbackreceiver.VerifyEventHash(secretKey, payload, ts, r.Header.Get("X-MP-Event-ID"), hash)
+		_, legacyErr = callbackreceiver.VerifyRequestHash(secretKey, payload, ts, hash)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cb := Init()
+	_, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+	})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	if verifyErr != nil {
+		t.Errorf("expected callback to be verified with its event id, but got %v", verifyErr)
+	}
+	if !errors.Is(legacyErr, callbackreceiver.ErrSignatureMismatch) {
+		t.Errorf("expected to get %v from the legacy verification, but got %v", callbackreceiver.ErrSignatureMismatch, legacyErr)
+	}
+}
+
+func TestSendCallbackEventSigningFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected callback that can not be signed not to be sent")
+	}))
+	defer server.Close()
+
+	cb := Init(WithSignatureVersions("v9"))
+	if _, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+	}); err == nil {
+		t.Fatalf("expected error for unsupported signature version")
+	}
+
+	list, err := cb.GetListOfEvents(context.Background(), "")
+	if err != nil || len(list.Data) != 1 {
+		t.Fatalf("expected the event to be recorded, but got %v, %v", list, err)
+	}
+	if e := list.Data[0]; e.Status != callback.StatusPending || e.RetryCount != 0 {
+		t.Errorf("expected event to stay %s without attempt, but got %s after %d attempts", callback.StatusPending, e.Status, e.RetryCount)
+	}
+}
+
+func TestGenerateEventHash(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	payload := []byte(`{"event":"payment_success"}`)
+
+	legacy, err := GenerateEventHash(payload, secretKey, ts)
+	if err != nil || len(legacy) != 64 || strings.Contains(legacy, "=") {
+		t.Errorf("expected legacy hex signature, but got %s, %v", legacy, err)
+	}
+
+	versioned, err := GenerateEventHash(payload, secretKey, ts, SignatureV1)
+	if err != nil || versioned != "t=1700000000,v1="+legacy {
+		t.Errorf("expected versioned header with the legacy signature as v1, but got %s, %v", versioned, err)
+	}
+
+	if _, err := GenerateEventHash(payload, secretKey, ts, "v9"); err == nil {
+		t.Errorf("expected error for unsupported version")
+	}
+}
+
+func TestSendCallbackEventStandardWebhooks(t *testing.T) {
+	secret := "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	verifier := callbackreceiver.NewVerifier(secret, callbackreceiver.WithStandardWebhooks())
+	var webhook *callbackreceiver.Webhook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// verify with the standard headers only, as an off-the-shelf verifier would
+		r.Header.Del("X-MP-SIGNATURE")
+		verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			webhook, _ = callbackreceiver.FromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	cb := Init(WithStandardWebhooks())
+	confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secret),
+		Method:        http.MethodPost,
+	})
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	if webhook == nil || webhook.EventID != confirmation.AcknowledgementID.String() {
+		t.Errorf("expected callback %s to be verified with the standard headers, but got %+v", confirmation.AcknowledgementID, webhook)
+	}
+}
+
+func TestGenerateStandardSignature(t *testing.T) {
+	// the test vector published with the Standard Webhooks specification
+	got, err := GenerateStandardSignature("msg_p5jXN8AQM9LWM0D4loKWxJek", time.Unix(1614265330, 0), []byte(`{"test": 2432232314}`), "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+	if err != nil {
+		t.Fatalf("expected to get nil error, but got %v", err)
+	}
+
+	want := "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="
+	if got != want {
+		t.Errorf("expected %s, but got %s", want, got)
+	}
+}
@@ -1,12 +1,55 @@
 package mock
 
-import callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+import (
+	"crypto/ed25519"
 
-func Init() callback.Client {
-	return &callbackClient{
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+type Option func(*callbackClient)
+
+// WithSigningKey makes the mock sign callbacks with the Ed25519 key of id,
+// in addition to the HMAC signature with the webhook secret of the event.
+func WithSigningKey(id string, key ed25519.PrivateKey) Option {
+	return func(c *callbackClient) {
+		c.signingKey = &signingKey{id: id, key: key}
+	}
+}
+
+// WithSignatureVersions makes the mock send the versioned signature header with a signature
+// of each version instead of the legacy one.
+func WithSignatureVersions(versions ...SignatureVersion) Option {
+	return func(c *callbackClient) {
+		c.signatureVersions = versions
+	}
+}
+
+// WithStandardWebhooks makes the mock also sign callbacks as in the Standard Webhooks specification,
+// with the webhook-id, webhook-timestamp and webhook-signature headers.
+func WithStandardWebhooks() Option {
+	return func(c *callbackClient) {
+		c.standardWebhooks = true
+	}
+}
+
+// WithJitterSource makes the mock spread retries by the Jitter of their retry policy,
+// with the random numbers in [0, 1) returned by r, e.g. rand.Float64.
+func WithJitterSource(r func() float64) Option {
+	return func(c *callbackClient) {
+		c.jitter = r
+	}
+}
+
+func Init(opts ...Option) callback.Client {
+	c := &callbackClient{
 		Service: Service{
-			Status: callback.StatusActive,
-			Events: make(map[string]*Event),
+			Status:       callback.ServiceStatusActive,
+			Events:       make(map[string]*Event),
+			Unsubscribed: make(map[string]bool),
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
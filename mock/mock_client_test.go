@@ -0,0 +1,192 @@
+package mock
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+func TestClientConcurrentUse(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+	cb := Init()
+
+	const senders = 20
+	ids := make(chan string, senders)
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := "/v1/callback"
+			if i%2 == 1 {
+				path = "/v1/error"
+			}
+			confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+				Payload:       map[string]interface{}{"event": "payment_success", "attempt": i},
+				CallbackURL:   server.URL + path,
+				WebhookSecret: callback.Secret(secretKey),
+				Method:        http.MethodPost,
+				MaxRetries:    5,
+				RetryPolicy: &callback.RetryPolicy{
+					Strategy:        callback.RetryStrategyFixed,
+					InitialInterval: time.Second,
+				},
+			})
+			if err == nil {
+				ids <- confirmation.AcknowledgementID.String()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				events, err := cb.GetListOfEvents(context.Background(), "")
+				if err != nil {
+					t.Errorf("expected to get nil error, but got %v", err)
+					return
+				}
+				for _, e := range events.Data {
+					if _, err := cb.GetEventDetailByID(context.Background(), e.ID.String()); err != nil {
+						t.Errorf("expected to get nil error, but got %v", err)
+						return
+					}
+					if _, err := cb.GetCallbackHistoryByEventID(context.Background(), e.ID.String(), ""); err != nil {
+						t.Errorf("expected to get nil error, but got %v", err)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(ids)
+
+	events, err := cb.GetListOfEvents(context.Background(), "")
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if events.MetaData.Total != senders {
+		t.Errorf("expected to get %d events, but got %d", senders, events.MetaData.Total)
+	}
+	if len(ids) != senders/2 {
+		t.Errorf("expected %d deliveries to succeed, but got %d", senders/2, len(ids))
+	}
+}
+
+func TestClientParallelTests(t *testing.T) {
+	server := InitTestCallbackServer()
+	t.Cleanup(server.Close)
+	cb := Init()
+
+	for _, name := range []string{"payment_success", "payment_failed", "refund", "chargeback"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+				Payload:       map[string]interface{}{"event": name},
+				CallbackURL:   server.URL + "/v1/callback",
+				WebhookSecret: callback.Secret(secretKey),
+				Method:        http.MethodPost,
+			})
+			if err != nil {
+				t.Errorf("expected to get nil error, but got %v", err)
+				return
+			}
+
+			event, err := cb.GetEventDetailByID(context.Background(), confirmation.AcknowledgementID.String())
+			if err != nil {
+				t.Errorf("expected to get nil error, but got %v", err)
+				return
+			}
+			if event.Payload["event"] != name {
+				t.Errorf("expected to get %s, but got %v", name, event.Payload["event"])
+			}
+		})
+	}
+}
+
+func TestClientReturnsCopies(t *testing.T) {
+	server := InitTestCallbackServer()
+	defer server.Close()
+	cb := Init()
+
+	payload := map[string]interface{}{
+		"event": "payment_success",
+		"items": []interface{}{map[string]interface{}{"sku": "A1"}},
+		"tags":  []string{"new"},
+		"meta":  map[string]string{"source": "api"},
+	}
+	policy := &callback.RetryPolicy{
+		Strategy: callback.RetryStrategySchedule,
+		Schedule: []time.Duration{time.Minute},
+	}
+	confirmation, err := cb.SendCallbackEvent(context.Background(), callback.CallbackRequestEvent{
+		Payload:       payload,
+		CallbackURL:   server.URL + "/v1/callback",
+		WebhookSecret: callback.Secret(secretKey),
+		Method:        http.MethodPost,
+		RetryPolicy:   policy,
+	})
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	id := confirmation.AcknowledgementID.String()
+
+	payload["event"] = "changed by caller"
+	payload["tags"].([]string)[0] = "changed by caller"
+	policy.Schedule[0] = time.Hour
+
+	event, _ := cb.GetEventDetailByID(context.Background(), id)
+	event.Payload["items"].([]interface{})[0].(map[string]interface{})["sku"] = "changed"
+	event.Payload["meta"].(map[string]string)["source"] = "changed"
+	event.RetryPolicy.Schedule[0] = time.Hour
+
+	events, _ := cb.GetListOfEvents(context.Background(), "")
+	events.Data[0].Payload["event"] = "changed"
+
+	history, _ := cb.GetCallbackHistoryByEventID(context.Background(), id, "")
+	history.Data[0].RequestHeaders.Set("X-MP-Event-ID", "changed")
+	history.Data[0].Event.Payload["event"] = "changed"
+
+	event, _ = cb.GetEventDetailByID(context.Background(), id)
+	if got := event.Payload["event"]; got != "payment_success" {
+		t.Errorf("expected payload event payment_success, but got %v", got)
+	}
+	if got := event.Payload["items"].([]interface{})[0].(map[string]interface{})["sku"]; got != "A1" {
+		t.Errorf("expected payload item sku A1, but got %v", got)
+	}
+	if got := event.Payload["tags"].([]string)[0]; got != "new" {
+		t.Errorf("expected payload tag new, but got %v", got)
+	}
+	if got := event.Payload["meta"].(map[string]string)["source"]; got != "api" {
+		t.Errorf("expected payload meta source api, but got %v", got)
+	}
+	if got := event.RetryPolicy.Schedule[0]; got != time.Minute {
+		t.Errorf("expected retry schedule %v, but got %v", time.Minute, got)
+	}
+
+	history, _ = cb.GetCallbackHistoryByEventID(context.Background(), id, "")
+	if got := history.Data[0].RequestHeaders.Get("X-MP-Event-ID"); got != id {
+		t.Errorf("expected X-MP-Event-ID %s, but got %s", id, got)
+	}
+}
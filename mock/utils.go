@@ -3,36 +3,250 @@ package mock
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/ed25519"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
 	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	callbackreceiver "dev.azure.com/2f-capital/go-packages/callback-client.git/callback_receiver"
+)
+
+type SignatureVersion string
+
+const (
+	// SignatureV1 is a hex HMAC-SHA256 in the versioned signature header.
+	SignatureV1 SignatureVersion = "v1"
+	// SignatureV2 is a hex HMAC-SHA512 in the versioned signature header.
+	SignatureV2 SignatureVersion = "v2"
 )
 
-func GenerateEventHash(payload []byte, sk string, t time.Time) (string, error) {
-	mac := hmac.New(sha256.New, []byte(sk))
+// GenerateEventHash returns the signature header of a payload signed at t, without an event id.
+// Without versions it is the legacy hex HMAC-SHA256, otherwise the versioned
+// "t=<t>,v1=<hex>,v2=<hex>" header with a signature of each version.
+func GenerateEventHash(payload []byte, sk string, t time.Time, versions ...SignatureVersion) (string, error) {
+	return GenerateEventHashWithID(payload, sk, t, "", versions...)
+}
 
-	_, err := mac.Write([]byte(fmt.Sprintf("%d", t.Unix())))
+// GenerateEventHashWithID is GenerateEventHash for a payload of the event eventID.
+// The signed material is "<t>.<eventID>.<payload>", or "<t>.<payload>" when eventID is empty.
+func GenerateEventHashWithID(payload []byte, sk string, t time.Time, eventID string, versions ...SignatureVersion) (string, error) {
+	h, err := callbackreceiver.Sign(sk, payload, callbackreceiver.SignOptions{
+		Time:     t,
+		EventID:  eventID,
+		Versions: receiverVersions(versions),
+	})
 	if err != nil {
 		return "", err
 	}
+	return h.Get(callbackreceiver.SignatureHeader), nil
+}
 
-	_, err = mac.Write([]byte("."))
+// receiverVersions converts versions to the signature versions of callbackreceiver.
+func receiverVersions(versions []SignatureVersion) []callbackreceiver.SignatureVersion {
+	if len(versions) == 0 {
+		return nil
+	}
+	converted := make([]callbackreceiver.SignatureVersion, len(versions))
+	for i, version := range versions {
+		converted[i] = callbackreceiver.SignatureVersion(version)
+	}
+	return converted
+}
+
+// GenerateStandardSignature returns the webhook-signature header of the Standard Webhooks specification,
+// "v1,<base64 HMAC-SHA256>" of "<msgID>.<t>.<payload>". The "whsec_" prefix of secret is optional
+// and the rest is the base64 key, secrets that are not base64 are used as is.
+func GenerateStandardSignature(msgID string, t time.Time, payload []byte, secret string) (string, error) {
+	h, err := callbackreceiver.Sign(secret, payload, callbackreceiver.SignOptions{Time: t, EventID: msgID, Standard: true})
 	if err != nil {
 		return "", err
 	}
+	return h.Get(callbackreceiver.StandardSignatureHeader), nil
+}
+
+// GenerateEventSignature returns the base64url Ed25519 signature of a payload of the event eventID signed at t.
+// The signed material is the same as the one of GenerateEventHashWithID.
+func GenerateEventSignature(payload []byte, key ed25519.PrivateKey, t time.Time, eventID string) string {
+	// without versions or standard signature, signing can not fail
+	h, _ := callbackreceiver.Sign("", payload, callbackreceiver.SignOptions{Time: t, EventID: eventID, Ed25519Key: key})
+	return h.Get(callbackreceiver.Ed25519SignatureHeader)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, in seconds or as an HTTP date relative to now.
+// It reports false for a missing or malformed header.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
 
-	_, err = mac.Write(payload)
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(header)
 	if err != nil {
-		return "", err
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// copyPayload returns a deep copy of payload, copying its nested maps and slices.
+func copyPayload(payload map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+// copyValue returns a deep copy of a payload value.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyPayload(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	case nil, string, bool, float64, int, int64, json.Number:
+		return v
+	default:
+		return copyReflect(reflect.ValueOf(v)).Interface()
+	}
+}
+
+// copyReflect returns a deep copy of v, copying typed maps, slices and pointers such as
+// []string or map[string]string that the payload may hold.
+func copyReflect(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), copyReflect(iter.Value()))
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			copied.Index(i).Set(copyReflect(v.Index(i)))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := range v.Len() {
+			copied.Index(i).Set(copyReflect(v.Index(i)))
+		}
+		return copied
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(copyReflect(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(copyReflect(v.Elem()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := range v.NumField() {
+			if field := copied.Field(i); field.CanSet() {
+				field.Set(copyReflect(v.Field(i)))
+			}
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// copyRetryPolicy returns a deep copy of p.
+func copyRetryPolicy(p *callback.RetryPolicy) *callback.RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	copied.Schedule = slices.Clone(p.Schedule)
+	copied.NonRetryableStatusCodes = slices.Clone(p.NonRetryableStatusCodes)
+	return &copied
+}
+
+// redactSignatures returns a copy of h with the signature headers redacted.
+func redactSignatures(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, name := range callback.SignatureHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+// readResponseSnapshot reads at most callback.ResponseBodyLimit bytes of body
+// and reports whether the body was longer than that.
+func readResponseSnapshot(body io.Reader) (string, bool) {
+	b, _ := io.ReadAll(io.LimitReader(body, callback.ResponseBodyLimit+1))
+	if len(b) > callback.ResponseBodyLimit {
+		return string(b[:callback.ResponseBodyLimit]), true
+	}
+	return string(b), false
+}
+
+// paginate returns the bounds of the page selected by filter over n items.
+// All items are selected when filter has no page size.
+func paginate(filter string, n int) (int, int, error) {
+	query, err := url.ParseQuery(filter)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if query.Get(callback.PerPageQueryKey) == "" {
+		return 0, n, nil
+	}
+
+	perPage, err := strconv.Atoi(query.Get(callback.PerPageQueryKey))
+	if err != nil || perPage < 1 {
+		return 0, 0, fmt.Errorf("invalid %s", callback.PerPageQueryKey)
+	}
+
+	page := 1
+	if query.Get(callback.PageQueryKey) != "" {
+		page, err = strconv.Atoi(query.Get(callback.PageQueryKey))
+		if err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("invalid %s", callback.PageQueryKey)
+		}
 	}
 
-	return hex.EncodeToString(mac.Sum(nil)), nil
+	start := min((page-1)*perPage, n)
+	return start, min(start+perPage, n), nil
 }
 
 func DoRequest(
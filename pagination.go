@@ -0,0 +1,84 @@
+package callbackclient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// PageQueryKey is the filter query parameter selecting the page, starting at 1.
+	PageQueryKey = "page"
+	// PerPageQueryKey is the filter query parameter selecting the page size.
+	PerPageQueryKey = "per_page"
+	// DefaultPerPage is the page size used when none is given.
+	DefaultPerPage = 50
+)
+
+// PageFilter returns filter with the page and page size query parameters set.
+func PageFilter(filter string, page, perPage int) (string, error) {
+	query, err := url.ParseQuery(filter)
+	if err != nil {
+		return "", err
+	}
+
+	query.Set(PageQueryKey, strconv.Itoa(page))
+	query.Set(PerPageQueryKey, strconv.Itoa(perPage))
+	return query.Encode(), nil
+}
+
+// EachEventPage calls fn with every page of events matching filter, starting at page.
+// It stops at the first error returned by the client or fn.
+func EachEventPage(ctx context.Context, client Client, filter string, page, perPage int, fn func(page int, list *EventList) error) error {
+	return eachPage(filter, page, perPage, func(filter string, page int) (int, int, error) {
+		list, err := client.GetListOfEvents(ctx, filter)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(list.Data) == 0 {
+			return 0, list.MetaData.Total, nil
+		}
+		return len(list.Data), list.MetaData.Total, fn(page, list)
+	})
+}
+
+// EachCallbackHistoryPage calls fn with every page of the callback history of an event matching filter,
+// starting at page. It stops at the first error returned by the client or fn.
+func EachCallbackHistoryPage(ctx context.Context, client Client, eventID, filter string, page, perPage int, fn func(page int, list *CallbackHistoryList) error) error {
+	return eachPage(filter, page, perPage, func(filter string, page int) (int, int, error) {
+		list, err := client.GetCallbackHistoryByEventID(ctx, eventID, filter)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(list.Data) == 0 {
+			return 0, list.MetaData.Total, nil
+		}
+		return len(list.Data), list.MetaData.Total, fn(page, list)
+	})
+}
+
+// eachPage fetches pages until a short or empty page, or until the total reported by the server is reached.
+func eachPage(filter string, page, perPage int, fetch func(filter string, page int) (count, total int, err error)) error {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+
+	for ; ; page++ {
+		pageFilter, err := PageFilter(filter, page, perPage)
+		if err != nil {
+			return err
+		}
+
+		count, total, err := fetch(pageFilter, page)
+		if err != nil {
+			return err
+		}
+
+		if count < perPage || (total > 0 && page*perPage >= total) {
+			return nil
+		}
+	}
+}
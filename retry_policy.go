@@ -0,0 +1,197 @@
+package callbackclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// RetryPolicy describes how the callback service spaces out retries of a failed callback.
+// On the wire the intervals are whole milliseconds, as in "initial_interval_ms":30000.
+type RetryPolicy struct {
+	// Strategy selects how the delay between attempts grows
+	Strategy RetryStrategy `json:"strategy,omitempty" example:"EXPONENTIAL"`
+	// InitialInterval is the delay before the first retry for the FIXED and EXPONENTIAL strategies
+	InitialInterval time.Duration `json:"initial_interval_ms,omitempty" swaggertype:"integer" example:"30000"`
+	// MaxInterval caps the delay between two attempts, zero means no cap
+	MaxInterval time.Duration `json:"max_interval_ms,omitempty" swaggertype:"integer" example:"3600000"`
+	// Schedule lists the delay before each retry for the SCHEDULE strategy.
+	// The last entry is reused once the schedule is exhausted
+	Schedule []time.Duration `json:"schedule_ms,omitempty" swaggertype:"array,integer"`
+	// Jitter randomly spreads each delay by up to this fraction of it, between 0 and 1
+	Jitter float64 `json:"jitter,omitempty" example:"0.2"`
+	// NonRetryableStatusCodes are callback response codes that stop any further retry
+	NonRetryableStatusCodes []int `json:"non_retryable_status_codes,omitempty" example:"400,404,410"`
+}
+
+// retryPolicyJSON is the wire format of RetryPolicy.
+type retryPolicyJSON struct {
+	Strategy                RetryStrategy `json:"strategy,omitempty"`
+	InitialInterval         int64         `json:"initial_interval_ms,omitempty"`
+	MaxInterval             int64         `json:"max_interval_ms,omitempty"`
+	Schedule                []int64       `json:"schedule_ms,omitempty"`
+	Jitter                  float64       `json:"jitter,omitempty"`
+	NonRetryableStatusCodes []int         `json:"non_retryable_status_codes,omitempty"`
+}
+
+// MarshalJSON encodes the intervals of the policy in milliseconds.
+func (p RetryPolicy) MarshalJSON() ([]byte, error) {
+	w := retryPolicyJSON{
+		Strategy:                p.Strategy,
+		InitialInterval:         p.InitialInterval.Milliseconds(),
+		MaxInterval:             p.MaxInterval.Milliseconds(),
+		Jitter:                  p.Jitter,
+		NonRetryableStatusCodes: p.NonRetryableStatusCodes,
+	}
+	for _, d := range p.Schedule {
+		w.Schedule = append(w.Schedule, d.Milliseconds())
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON decodes a policy with intervals in milliseconds.
+func (p *RetryPolicy) UnmarshalJSON(data []byte) error {
+	var w retryPolicyJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+
+	*p = RetryPolicy{
+		Strategy:                w.Strategy,
+		InitialInterval:         time.Duration(w.InitialInterval) * time.Millisecond,
+		MaxInterval:             time.Duration(w.MaxInterval) * time.Millisecond,
+		Jitter:                  w.Jitter,
+		NonRetryableStatusCodes: w.NonRetryableStatusCodes,
+	}
+	for _, ms := range w.Schedule {
+		p.Schedule = append(p.Schedule, time.Duration(ms)*time.Millisecond)
+	}
+	return nil
+}
+
+func (p RetryPolicy) Validate() error {
+	interval := validation.By(func(value interface{}) error {
+		d, _ := value.(time.Duration)
+		if d < 0 {
+			return fmt.Errorf("interval must not be negative")
+		}
+		if d%time.Millisecond != 0 {
+			return fmt.Errorf("interval must be a whole number of milliseconds")
+		}
+		return nil
+	})
+
+	return newValidationError(validation.Errors{
+		"strategy": validation.Validate(p.Strategy,
+			validation.Required.Error("strategy is required"),
+			validation.In(RetryStrategyFixed, RetryStrategyExponential, RetryStrategySchedule).Error("invalid strategy"),
+		),
+		"initial_interval": validation.Validate(p.InitialInterval,
+			interval,
+			validation.By(func(interface{}) error {
+				if p.Strategy != RetryStrategySchedule && p.InitialInterval == 0 {
+					return fmt.Errorf("initial interval is required")
+				}
+				return nil
+			}),
+		),
+		"max_interval": validation.Validate(p.MaxInterval,
+			interval,
+			validation.By(func(interface{}) error {
+				if p.MaxInterval > 0 && p.MaxInterval < p.InitialInterval {
+					return fmt.Errorf("max interval must not be less than initial interval")
+				}
+				return nil
+			}),
+		),
+		"schedule": validation.Validate(p.Schedule,
+			validation.By(func(interface{}) error {
+				if p.Strategy == RetryStrategySchedule && len(p.Schedule) == 0 {
+					return fmt.Errorf("schedule is required")
+				}
+				if p.Strategy != RetryStrategySchedule && len(p.Schedule) > 0 {
+					return fmt.Errorf("schedule is only allowed with the SCHEDULE strategy")
+				}
+				return nil
+			}),
+			validation.Each(validation.By(func(value interface{}) error {
+				d, _ := value.(time.Duration)
+				if d <= 0 {
+					return fmt.Errorf("schedule intervals must be positive")
+				}
+				if d%time.Millisecond != 0 {
+					return fmt.Errorf("schedule intervals must be whole numbers of milliseconds")
+				}
+				return nil
+			})),
+		),
+		"jitter": validation.Validate(p.Jitter,
+			validation.Min(0.0).Error("jitter must be between 0 and 1"),
+			validation.Max(1.0).Error("jitter must be between 0 and 1"),
+		),
+		"non_retryable_status_codes": validation.Validate(p.NonRetryableStatusCodes,
+			validation.Each(validation.By(func(value interface{}) error {
+				code, _ := value.(int)
+				if code < 100 || code > 599 {
+					return fmt.Errorf("invalid status code %d", code)
+				}
+				if code >= 200 && code < 300 {
+					return fmt.Errorf("status code %d is a success code", code)
+				}
+				return nil
+			})),
+		),
+	})
+}
+
+// Backoff returns the delay before the given retry attempt, starting at 1, without jitter.
+func (p RetryPolicy) Backoff(attempt int64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	var d time.Duration
+	switch p.Strategy {
+	case RetryStrategyFixed:
+		d = p.InitialInterval
+	case RetryStrategyExponential:
+		factor := math.Pow(2, float64(attempt-1))
+		if f := float64(p.InitialInterval) * factor; f >= math.MaxInt64 {
+			d = time.Duration(math.MaxInt64)
+		} else {
+			d = time.Duration(f)
+		}
+	case RetryStrategySchedule:
+		if len(p.Schedule) == 0 {
+			return 0
+		}
+		d = p.Schedule[min(attempt, int64(len(p.Schedule)))-1]
+	}
+
+	if p.MaxInterval > 0 && d > p.MaxInterval {
+		d = p.MaxInterval
+	}
+	return d
+}
+
+// ApplyJitter spreads d by up to Jitter of it.
+// r is a random number in [0, 1), 0.5 leaves d unchanged.
+func (p RetryPolicy) ApplyJitter(d time.Duration, r float64) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	return d + time.Duration(float64(d)*p.Jitter*(2*r-1))
+}
+
+// IsRetryableStatus reports whether a callback that got the given response code may be retried.
+func (p RetryPolicy) IsRetryableStatus(code int) bool {
+	for _, c := range p.NonRetryableStatusCodes {
+		if c == code {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package callbackclient_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+func TestRetryPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     callback.RetryPolicy
+		wantFields []string
+	}{
+		{
+			name:   "accept exponential policy",
+			policy: callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Second, MaxInterval: time.Minute, Jitter: 0.2},
+		},
+		{
+			name:   "accept schedule policy",
+			policy: callback.RetryPolicy{Strategy: callback.RetryStrategySchedule, Schedule: []time.Duration{time.Minute, time.Hour}},
+		},
+		{
+			name:       "reject missing strategy",
+			policy:     callback.RetryPolicy{InitialInterval: time.Second},
+			wantFields: []string{"strategy"},
+		},
+		{
+			name:       "reject unknown strategy",
+			policy:     callback.RetryPolicy{Strategy: "LINEAR", InitialInterval: time.Second},
+			wantFields: []string{"strategy"},
+		},
+		{
+			name:       "reject missing initial interval",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed},
+			wantFields: []string{"initial_interval"},
+		},
+		{
+			name:       "reject sub millisecond interval",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: time.Second + time.Microsecond},
+			wantFields: []string{"initial_interval"},
+		},
+		{
+			name:       "reject max interval below initial interval",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: time.Minute, MaxInterval: time.Second},
+			wantFields: []string{"max_interval"},
+		},
+		{
+			name:       "reject schedule strategy without schedule",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategySchedule},
+			wantFields: []string{"schedule"},
+		},
+		{
+			name:       "reject schedule with other strategy",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: time.Second, Schedule: []time.Duration{time.Minute}},
+			wantFields: []string{"schedule"},
+		},
+		{
+			name:       "reject non positive schedule entry",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategySchedule, Schedule: []time.Duration{time.Minute, 0}},
+			wantFields: []string{"schedule"},
+		},
+		{
+			name:       "reject jitter over 1",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: time.Second, Jitter: 1.5},
+			wantFields: []string{"jitter"},
+		},
+		{
+			name:       "reject success and unknown status codes",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: time.Second, NonRetryableStatusCodes: []int{200}},
+			wantFields: []string{"non_retryable_status_codes"},
+		},
+		{
+			name:       "reject status code out of range",
+			policy:     callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: time.Second, NonRetryableStatusCodes: []int{404, 600}},
+			wantFields: []string{"non_retryable_status_codes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Errorf("expected to get nil error, but got %v", err)
+				}
+				return
+			}
+
+			var verr *callback.ValidationError
+			if !errors.As(err, &verr) {
+				t.Errorf("expected to get a validation error, but got %v", err)
+				return
+			}
+			var got []string
+			for _, f := range verr.FieldError {
+				got = append(got, f.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("expected fields %v, but got %v", tt.wantFields, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  callback.RetryPolicy
+		attempt int64
+		want    time.Duration
+	}{
+		{
+			name:    "keep fixed interval",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: 30 * time.Second},
+			attempt: 7,
+			want:    30 * time.Second,
+		},
+		{
+			name:    "start exponential backoff at initial interval",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Second},
+			attempt: 1,
+			want:    time.Second,
+		},
+		{
+			name:    "treat attempts below 1 as the first",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Second},
+			attempt: 0,
+			want:    time.Second,
+		},
+		{
+			name:    "double exponential backoff",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Second},
+			attempt: 4,
+			want:    8 * time.Second,
+		},
+		{
+			name:    "cap exponential backoff at max interval",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Second, MaxInterval: time.Minute},
+			attempt: 10,
+			want:    time.Minute,
+		},
+		{
+			name:    "saturate exponential overflow",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Hour},
+			attempt: 100,
+			want:    time.Duration(math.MaxInt64),
+		},
+		{
+			name:    "cap exponential overflow at max interval",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategyExponential, InitialInterval: time.Hour, MaxInterval: 24 * time.Hour},
+			attempt: 100,
+			want:    24 * time.Hour,
+		},
+		{
+			name:    "follow schedule",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategySchedule, Schedule: []time.Duration{time.Minute, 5 * time.Minute, time.Hour}},
+			attempt: 2,
+			want:    5 * time.Minute,
+		},
+		{
+			name:    "reuse last schedule entry",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategySchedule, Schedule: []time.Duration{time.Minute, 5 * time.Minute, time.Hour}},
+			attempt: 9,
+			want:    time.Hour,
+		},
+		{
+			name:    "return zero for empty schedule",
+			policy:  callback.RetryPolicy{Strategy: callback.RetryStrategySchedule},
+			attempt: 1,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.attempt); got != tt.want {
+				t.Errorf("expected to get %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyApplyJitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		jitter float64
+		r      float64
+		want   time.Duration
+	}{
+		{name: "leave delay without jitter", jitter: 0, r: 0, want: time.Minute},
+		{name: "shorten delay by full jitter", jitter: 0.5, r: 0, want: 30 * time.Second},
+		{name: "keep delay at middle", jitter: 0.5, r: 0.5, want: time.Minute},
+		{name: "lengthen delay", jitter: 0.5, r: 0.75, want: 75 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := callback.RetryPolicy{Jitter: tt.jitter}
+			if got := policy.ApplyJitter(time.Minute, tt.r); got != tt.want {
+				t.Errorf("expected to get %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyJSON(t *testing.T) {
+	policy := callback.RetryPolicy{
+		Strategy:                callback.RetryStrategySchedule,
+		MaxInterval:             time.Hour,
+		Schedule:                []time.Duration{30 * time.Second, 5 * time.Minute},
+		Jitter:                  0.2,
+		NonRetryableStatusCodes: []int{400, 404},
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	want := `{"strategy":"SCHEDULE","max_interval_ms":3600000,"schedule_ms":[30000,300000],"jitter":0.2,"non_retryable_status_codes":[400,404]}`
+	if string(data) != want {
+		t.Errorf("expected to get %s, but got %s", want, data)
+	}
+
+	var got callback.RetryPolicy
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got, policy) {
+		t.Errorf("expected to get %+v, but got %+v", policy, got)
+	}
+
+	var fixed callback.RetryPolicy
+	if err := json.Unmarshal([]byte(`{"strategy":"FIXED","initial_interval_ms":1500}`), &fixed); err != nil || fixed.InitialInterval != 1500*time.Millisecond {
+		t.Errorf("expected initial interval 1.5s, but got %v, %v", fixed.InitialInterval, err)
+	}
+}
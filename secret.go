@@ -0,0 +1,50 @@
+package callbackclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// Secret holds a credential such as a webhook secret or a service token.
+// It is redacted when formatted, logged or marshalled to JSON.
+// Use Reveal to get the actual value.
+type Secret string
+
+// Reveal returns the actual value of the secret.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) redacted() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+func (s Secret) String() string {
+	return s.redacted()
+}
+
+// Format redacts the secret for every verb, including %#v and %+v of enclosing structs.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'q' {
+		fmt.Fprintf(f, "%q", s.redacted())
+		return
+	}
+	_, _ = io.WriteString(f, s.redacted())
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.redacted())
+}
+
+// MarshalJSON redacts the secret. Use the raw marshalling of the enclosing type,
+// e.g. CallbackRequestEvent.MarshalRawJSON, where the actual value must be sent.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.redacted())
+}
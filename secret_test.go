@@ -0,0 +1,149 @@
+package callbackclient_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"github.com/google/uuid"
+)
+
+const leakedSecret = "whsec_do_not_leak_this_value"
+
+func TestSecretFormat(t *testing.T) {
+	event := callback.Event{
+		ID:            uuid.New(),
+		CallbackURL:   "https://api.example.com/callback",
+		WebhookSecret: leakedSecret,
+		Service:       callback.Service{Name: "payments", SecretToken: leakedSecret},
+	}
+	service := callback.Service{Name: "payments", SecretToken: leakedSecret}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+		for name, value := range map[string]interface{}{
+			"event":         event,
+			"event pointer": &event,
+			"service":       service,
+			"secret":        callback.Secret(leakedSecret),
+		} {
+			t.Run(name+" "+format, func(t *testing.T) {
+				got := fmt.Sprintf(format, value)
+				if strings.Contains(got, leakedSecret) {
+					t.Errorf("expected secret to be redacted, but got %s", got)
+				}
+				if name != "event pointer" && !strings.Contains(got, "[REDACTED]") {
+					t.Errorf("expected redaction marker, but got %s", got)
+				}
+			})
+		}
+	}
+
+	if got := fmt.Sprintf("%q", callback.Secret(leakedSecret)); got != `"[REDACTED]"` {
+		t.Errorf("expected to get %q, but got %s", `"[REDACTED]"`, got)
+	}
+	if got := fmt.Sprintf("%v", callback.Secret("")); got != "" {
+		t.Errorf("expected empty secret to stay empty, but got %q", got)
+	}
+}
+
+func TestSecretLogValue(t *testing.T) {
+	event := callback.Event{WebhookSecret: leakedSecret, Service: callback.Service{SecretToken: leakedSecret}}
+
+	for name, newHandler := range map[string]func(*bytes.Buffer) slog.Handler{
+		"text": func(b *bytes.Buffer) slog.Handler { return slog.NewTextHandler(b, nil) },
+		"json": func(b *bytes.Buffer) slog.Handler { return slog.NewJSONHandler(b, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(newHandler(&out))
+			logger.Info("callback", "secret", callback.Secret(leakedSecret), "event", event, "service", event.Service)
+
+			if strings.Contains(out.String(), leakedSecret) {
+				t.Errorf("expected secret to be redacted, but got %s", out.String())
+			}
+			if !strings.Contains(out.String(), "[REDACTED]") {
+				t.Errorf("expected redaction marker, but got %s", out.String())
+			}
+		})
+	}
+
+	if got := callback.Secret(leakedSecret).LogValue().String(); got != "[REDACTED]" {
+		t.Errorf("expected to get [REDACTED], but got %s", got)
+	}
+}
+
+func TestSecretMarshalJSON(t *testing.T) {
+	event := callback.Event{WebhookSecret: leakedSecret, Service: callback.Service{SecretToken: leakedSecret}}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if strings.Contains(string(data), leakedSecret) {
+		t.Errorf("expected secret to be redacted, but got %s", data)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	if got := decoded["webhook_secret"]; got != "[REDACTED]" {
+		t.Errorf("expected redacted webhook secret, but got %v", got)
+	}
+	if got := decoded["service"].(map[string]interface{})["secret_token"]; got != "[REDACTED]" {
+		t.Errorf("expected redacted secret token, but got %v", got)
+	}
+
+	data, _ = json.Marshal(callback.Event{})
+	if strings.Contains(string(data), "webhook_secret") {
+		t.Errorf("expected empty secret to be omitted, but got %s", data)
+	}
+}
+
+func TestCallbackRequestEventMarshalRawJSON(t *testing.T) {
+	request := callback.CallbackRequestEvent{
+		ServiceID:     uuid.New(),
+		Payload:       map[string]interface{}{"event": "payment_success", "amount": 100.5},
+		CallbackURL:   "https://api.example.com/callback",
+		WebhookSecret: leakedSecret,
+		Method:        "POST",
+		MaxRetries:    5,
+		RetryPolicy:   &callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: 30 * time.Second},
+	}
+
+	data, err := request.MarshalRawJSON()
+	if err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+	want := map[string]interface{}{
+		"payload":        map[string]interface{}{"event": "payment_success", "amount": 100.5},
+		"callback_url":   "https://api.example.com/callback",
+		"webhook_secret": leakedSecret,
+		"method":         "POST",
+		"max_retries":    float64(5),
+		"retry_policy":   map[string]interface{}{"strategy": "FIXED", "initial_interval_ms": float64(30000)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected to get %v, but got %v", want, got)
+	}
+
+	data, _ = json.Marshal(request)
+	if strings.Contains(string(data), leakedSecret) {
+		t.Errorf("expected plain marshalling to redact the secret, but got %s", data)
+	}
+}
@@ -0,0 +1,70 @@
+package callbackclient
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// statusTransitions lists the statuses each delivery status may move to.
+var statusTransitions = map[Status][]Status{
+	StatusPending:    {StatusProcessing, StatusFailed},
+	StatusProcessing: {StatusSucceeded, StatusRetrying, StatusFailed},
+	StatusRetrying:   {StatusProcessing, StatusFailed},
+	StatusSucceeded:  {},
+	StatusFailed:     {},
+}
+
+// IsValid reports whether s is a known delivery status.
+func (s Status) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether no further delivery attempt will be made for an event in status s.
+func (s Status) IsTerminal() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+// IsRetryable reports whether an event in status s is still waiting for a delivery attempt.
+func (s Status) IsRetryable() bool {
+	return s == StatusPending || s == StatusRetrying
+}
+
+// CanTransitionTo reports whether an event may move from status s to next.
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, to := range statusTransitions[s] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyStatusActive is the status older callback servers give to events not yet delivered.
+const legacyStatusActive = "ACTIVE"
+
+// UnmarshalJSON rejects statuses that are not delivery statuses.
+// An empty string is kept as the zero value. The legacy ACTIVE status of new events
+// is decoded as StatusPending while servers still send it.
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v == legacyStatusActive {
+		v = string(StatusPending)
+	}
+
+	status := Status(v)
+	if status != "" && !status.IsValid() {
+		return fmt.Errorf("invalid status %q", v)
+	}
+
+	*s = status
+	return nil
+}
+
+// IsValid reports whether s is a known service status.
+func (s ServiceStatus) IsValid() bool {
+	return s == ServiceStatusActive || s == ServiceStatusInactive
+}
@@ -0,0 +1,131 @@
+package callbackclient_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+)
+
+func TestStatusUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    callback.Status
+		wantErr bool
+	}{
+		{name: "decode delivery status", data: `"RETRYING"`, want: callback.StatusRetrying},
+		{name: "keep empty status", data: `""`, want: ""},
+		{name: "decode legacy active status as pending", data: `"ACTIVE"`, want: callback.StatusPending},
+		{name: "reject service status", data: `"INACTIVE"`, wantErr: true},
+		{name: "reject unknown status", data: `"DELIVERED"`, wantErr: true},
+		{name: "reject lower case status", data: `"pending"`, wantErr: true},
+		{name: "reject non string status", data: `1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got callback.Status
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error to be %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("expected to get %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEventListLegacyStatus(t *testing.T) {
+	var list callback.EventList
+	if err := json.Unmarshal([]byte(`{"data":[{"status":"ACTIVE"},{"status":"SUCCEEDED"}]}`), &list); err != nil {
+		t.Errorf("expected to get nil error, but got %v", err)
+		return
+	}
+
+	if got := list.Data[0].Status; got != callback.StatusPending {
+		t.Errorf("expected to get %s, but got %s", callback.StatusPending, got)
+	}
+	if got := list.Data[1].Status; got != callback.StatusSucceeded {
+		t.Errorf("expected to get %s, but got %s", callback.StatusSucceeded, got)
+	}
+}
+
+func TestStatusPredicates(t *testing.T) {
+	tests := []struct {
+		status        callback.Status
+		wantValid     bool
+		wantTerminal  bool
+		wantRetryable bool
+	}{
+		{status: callback.StatusPending, wantValid: true, wantRetryable: true},
+		{status: callback.StatusProcessing, wantValid: true},
+		{status: callback.StatusRetrying, wantValid: true, wantRetryable: true},
+		{status: callback.StatusSucceeded, wantValid: true, wantTerminal: true},
+		{status: callback.StatusFailed, wantValid: true, wantTerminal: true},
+		{status: callback.StatusActive},
+		{status: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := tt.status.IsValid(); got != tt.wantValid {
+				t.Errorf("expected valid to be %v, but got %v", tt.wantValid, got)
+			}
+			if got := tt.status.IsTerminal(); got != tt.wantTerminal {
+				t.Errorf("expected terminal to be %v, but got %v", tt.wantTerminal, got)
+			}
+			if got := tt.status.IsRetryable(); got != tt.wantRetryable {
+				t.Errorf("expected retryable to be %v, but got %v", tt.wantRetryable, got)
+			}
+		})
+	}
+}
+
+func TestStatusCanTransitionTo(t *testing.T) {
+	statuses := []callback.Status{
+		callback.StatusPending,
+		callback.StatusProcessing,
+		callback.StatusRetrying,
+		callback.StatusSucceeded,
+		callback.StatusFailed,
+	}
+	allowed := map[[2]callback.Status]bool{
+		{callback.StatusPending, callback.StatusProcessing}:   true,
+		{callback.StatusPending, callback.StatusFailed}:       true,
+		{callback.StatusProcessing, callback.StatusSucceeded}: true,
+		{callback.StatusProcessing, callback.StatusRetrying}:  true,
+		{callback.StatusProcessing, callback.StatusFailed}:    true,
+		{callback.StatusRetrying, callback.StatusProcessing}:  true,
+		{callback.StatusRetrying, callback.StatusFailed}:      true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]callback.Status{from, to}]
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("expected transition from %s to %s to be %v, but got %v", from, to, want, got)
+			}
+		}
+		if from.CanTransitionTo("UNKNOWN") {
+			t.Errorf("expected transition from %s to an unknown status to be rejected", from)
+		}
+	}
+	if callback.Status("UNKNOWN").CanTransitionTo(callback.StatusProcessing) {
+		t.Errorf("expected transition from an unknown status to be rejected")
+	}
+}
+
+func TestServiceStatusIsValid(t *testing.T) {
+	for status, want := range map[callback.ServiceStatus]bool{
+		callback.ServiceStatusActive:   true,
+		callback.ServiceStatusInactive: true,
+		"PENDING":                      false,
+	} {
+		if got := status.IsValid(); got != want {
+			t.Errorf("expected %s valid to be %v, but got %v", status, want, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package callbackclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// ValidationError is returned when a request fails client-side validation.
+// FieldError has the same shape as the field errors returned by the callback service.
+type ValidationError struct {
+	FieldError []FieldError `json:"field_error"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.FieldError))
+	for _, f := range e.FieldError {
+		msgs = append(msgs, f.Name+": "+f.Description)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// newValidationError converts ozzo validation errors into a ValidationError.
+// Nested validation errors are flattened as "parent.field". It returns nil when errs holds no errors.
+func newValidationError(errs validation.Errors) error {
+	if err := errs.Filter(); err == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	verr := &ValidationError{}
+	for _, name := range names {
+		var nested *ValidationError
+		if errors.As(errs[name], &nested) {
+			for _, f := range nested.FieldError {
+				verr.FieldError = append(verr.FieldError, FieldError{
+					Name:        name + "." + f.Name,
+					Description: f.Description,
+				})
+			}
+			continue
+		}
+
+		verr.FieldError = append(verr.FieldError, FieldError{
+			Name:        name,
+			Description: errs[name].Error(),
+		})
+	}
+	return verr
+}
+
+// ErrURLNotAllowed is returned when a callback url is rejected by a URLPolicy.
+var ErrURLNotAllowed = errors.New("callback url is not allowed")
+
+// URLPolicy restricts the callback urls an event may be sent to.
+// The zero value accepts any url.
+type URLPolicy struct {
+	// RequireHTTPS rejects urls that do not use the https scheme.
+	RequireHTTPS bool
+	// BlockPrivateNetworks rejects loopback, link-local, private-range
+	// and unspecified hosts to prevent SSRF.
+	BlockPrivateNetworks bool
+	// AllowedHosts, if not empty, is the list of hosts urls may point to.
+	// An entry starting with "." matches any subdomain of it.
+	// It does not lift the other restrictions of the policy.
+	AllowedHosts []string
+	// LookupIP resolves host names so their addresses can be checked
+	// when BlockPrivateNetworks is set. Only ip literals are checked if it is nil.
+	LookupIP func(host string) ([]net.IP, error)
+}
+
+// SecureURLPolicy returns a policy that requires https and blocks private networks,
+// resolving host names with net.LookupIP.
+func SecureURLPolicy(allowedHosts ...string) URLPolicy {
+	return URLPolicy{
+		RequireHTTPS:         true,
+		BlockPrivateNetworks: true,
+		AllowedHosts:         allowedHosts,
+		LookupIP:             net.LookupIP,
+	}
+}
+
+// Validate checks rawURL against the policy.
+// The zero policy accepts any url, even one without a scheme or host.
+func (p URLPolicy) Validate(rawURL string) error {
+	if !p.RequireHTTPS && !p.BlockPrivateNetworks && len(p.AllowedHosts) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: invalid url", ErrURLNotAllowed)
+	}
+
+	if p.RequireHTTPS && u.Scheme != "https" {
+		return fmt.Errorf("%w: https is required", ErrURLNotAllowed)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if len(p.AllowedHosts) > 0 && !p.hostAllowed(host) {
+		return fmt.Errorf("%w: host %s is not in the allowlist", ErrURLNotAllowed, host)
+	}
+
+	if p.BlockPrivateNetworks {
+		return p.checkPublicHost(host)
+	}
+
+	return nil
+}
+
+func (p URLPolicy) hostAllowed(host string) bool {
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, ".") {
+			if strings.HasSuffix(host, allowed) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (p URLPolicy) checkPublicHost(host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: host %s is a loopback host", ErrURLNotAllowed, host)
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if p.LookupIP == nil {
+			return nil
+		}
+
+		var err error
+		ips, err = p.LookupIP(host)
+		if err != nil {
+			return fmt.Errorf("%w: failed to resolve host %s", ErrURLNotAllowed, host)
+		}
+	}
+
+	for _, ip := range ips {
+		if isPrivateIP(ip) {
+			return fmt.Errorf("%w: host %s resolves to a private address", ErrURLNotAllowed, host)
+		}
+	}
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}
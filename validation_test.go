@@ -0,0 +1,190 @@
+package callbackclient_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	callback "dev.azure.com/2f-capital/go-packages/callback-client.git"
+	"github.com/google/uuid"
+)
+
+func TestURLPolicyValidate(t *testing.T) {
+	lookup := func(host string) ([]net.IP, error) {
+		switch host {
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.7")}, nil
+		case "api.example.com", "hooks.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+	secure := callback.URLPolicy{RequireHTTPS: true, BlockPrivateNetworks: true, LookupIP: lookup}
+
+	tests := []struct {
+		name    string
+		policy  callback.URLPolicy
+		url     string
+		wantErr bool
+	}{
+		{name: "accept any url with zero policy", url: "http://127.0.0.1/callback"},
+		{name: "accept url without scheme with zero policy", url: "service.com/callback"},
+		{name: "reject url without scheme", policy: callback.URLPolicy{RequireHTTPS: true}, url: "service.com/callback", wantErr: true},
+		{name: "accept public https url", policy: secure, url: "https://api.example.com/callback"},
+		{name: "reject http when https is required", policy: secure, url: "http://api.example.com/callback", wantErr: true},
+		{name: "reject url without host", policy: secure, url: "https:///callback", wantErr: true},
+		{name: "reject localhost", policy: secure, url: "https://localhost/callback", wantErr: true},
+		{name: "reject localhost subdomain", policy: secure, url: "https://app.localhost/callback", wantErr: true},
+		{name: "reject ipv4 loopback", policy: secure, url: "https://127.0.0.1/callback", wantErr: true},
+		{name: "reject ipv6 loopback", policy: secure, url: "https://[::1]/callback", wantErr: true},
+		{name: "reject ipv4 mapped loopback", policy: secure, url: "https://[::ffff:127.0.0.1]/callback", wantErr: true},
+		{name: "reject private 10/8", policy: secure, url: "https://10.1.2.3/callback", wantErr: true},
+		{name: "reject link local 169.254/16", policy: secure, url: "https://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "reject unique local fc00::/7", policy: secure, url: "https://[fd12:3456::1]/callback", wantErr: true},
+		{name: "reject unspecified address", policy: secure, url: "https://0.0.0.0/callback", wantErr: true},
+		{name: "reject public host resolving to private address", policy: secure, url: "https://internal.example.com/callback", wantErr: true},
+		{name: "reject host that does not resolve", policy: secure, url: "https://unknown.example.org/callback", wantErr: true},
+		{
+			name:   "accept subdomain of allowlisted domain",
+			policy: callback.URLPolicy{AllowedHosts: []string{".example.com"}},
+			url:    "https://hooks.example.com/callback",
+		},
+		{
+			name:   "match allowlist case insensitively",
+			policy: callback.URLPolicy{AllowedHosts: []string{".Example.com"}},
+			url:    "https://HOOKS.example.com./callback",
+		},
+		{
+			name:    "reject apex of subdomain allowlist entry",
+			policy:  callback.URLPolicy{AllowedHosts: []string{".example.com"}},
+			url:     "https://example.com/callback",
+			wantErr: true,
+		},
+		{
+			name:    "reject host only sharing the allowlist suffix",
+			policy:  callback.URLPolicy{AllowedHosts: []string{".example.com"}},
+			url:     "https://evilexample.com/callback",
+			wantErr: true,
+		},
+		{
+			name:   "accept exact allowlist entry",
+			policy: callback.URLPolicy{AllowedHosts: []string{"example.com"}},
+			url:    "https://example.com/callback",
+		},
+		{
+			name:    "keep blocking private networks for allowlisted host",
+			policy:  callback.URLPolicy{BlockPrivateNetworks: true, AllowedHosts: []string{"127.0.0.1"}},
+			url:     "https://127.0.0.1/callback",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error to be %v, but got %v", tt.wantErr, err)
+				return
+			}
+			if err != nil && !errors.Is(err, callback.ErrURLNotAllowed) {
+				t.Errorf("expected to get %v, but got %v", callback.ErrURLNotAllowed, err)
+			}
+		})
+	}
+}
+
+func TestCallbackRequestEventValidate(t *testing.T) {
+	valid := callback.CallbackRequestEvent{
+		ServiceID:     uuid.New(),
+		Payload:       map[string]interface{}{"event": "payment_success"},
+		CallbackURL:   "https://api.example.com/callback",
+		WebhookSecret: "test webhook secret key",
+		Method:        "POST",
+		MaxRetries:    5,
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(r *callback.CallbackRequestEvent)
+		policy     callback.URLPolicy
+		wantFields []string
+	}{
+		{name: "accept valid request", modify: func(r *callback.CallbackRequestEvent) {}},
+		{
+			name:   "accept callback url without scheme",
+			modify: func(r *callback.CallbackRequestEvent) { r.CallbackURL = "service.com/callback" },
+		},
+		{
+			name:   "accept lowest max retries",
+			modify: func(r *callback.CallbackRequestEvent) { r.MaxRetries = callback.MinRetries },
+		},
+		{
+			name:   "accept highest max retries",
+			modify: func(r *callback.CallbackRequestEvent) { r.MaxRetries = callback.MaxRetriesLimit },
+		},
+		{
+			name:       "reject negative max retries",
+			modify:     func(r *callback.CallbackRequestEvent) { r.MaxRetries = callback.MinRetries - 1 },
+			wantFields: []string{"max_retries"},
+		},
+		{
+			name:       "reject max retries over the limit",
+			modify:     func(r *callback.CallbackRequestEvent) { r.MaxRetries = callback.MaxRetriesLimit + 1 },
+			wantFields: []string{"max_retries"},
+		},
+		{
+			name: "report every invalid field in name order",
+			modify: func(r *callback.CallbackRequestEvent) {
+				r.ServiceID = uuid.Nil
+				r.Payload = map[string]interface{}{}
+				r.WebhookSecret = ""
+				r.Method = "TRACE"
+			},
+			wantFields: []string{"method", "payload", "service_id", "webhook_secret"},
+		},
+		{
+			name:       "reject callback url rejected by policy",
+			modify:     func(r *callback.CallbackRequestEvent) { r.CallbackURL = "http://api.example.com/callback" },
+			policy:     callback.URLPolicy{RequireHTTPS: true},
+			wantFields: []string{"callback_url"},
+		},
+		{
+			name: "flatten nested retry policy fields",
+			modify: func(r *callback.CallbackRequestEvent) {
+				r.RetryPolicy = &callback.RetryPolicy{Strategy: callback.RetryStrategyFixed, InitialInterval: -time.Second}
+			},
+			wantFields: []string{"retry_policy.initial_interval"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.modify(&r)
+
+			err := r.ValidateWithURLPolicy(tt.policy)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Errorf("expected to get nil error, but got %v", err)
+				}
+				return
+			}
+
+			var verr *callback.ValidationError
+			if !errors.As(err, &verr) {
+				t.Errorf("expected to get a validation error, but got %v", err)
+				return
+			}
+			var got []string
+			for _, f := range verr.FieldError {
+				got = append(got, f.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("expected fields %v, but got %v", tt.wantFields, got)
+			}
+		})
+	}
+}